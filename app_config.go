@@ -1,6 +1,13 @@
 package gow
 
-import "github.com/gkzy/gow/lib/config"
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gkzy/gow/lib/config"
+	"github.com/gkzy/gow/lib/logy"
+)
 
 // AppConfig gow app 统一配置入口
 //		可以通过AppConfig完成统一的app基础配置
@@ -14,6 +21,19 @@ type AppConfig struct {
 	TemplateRight string //模板符号
 	SessionOn     bool   //是否打开session
 	GzipOn        bool   // 是否打开gzip
+
+	ReadTimeout       time.Duration // 读取整个请求的超时时间，0为不限制
+	ReadHeaderTimeout time.Duration // 读取请求头的超时时间
+	WriteTimeout      time.Duration // 写响应的超时时间，0为不限制
+	IdleTimeout       time.Duration // keep-alive 连接的空闲时间
+	MaxHeaderBytes    int           // 请求头的最大字节数
+	ShutdownTimeout   time.Duration // 退出时等待请求处理完的时间
+	HotRestart        bool          // 是否在 SIGHUP、SIGUSR2 时热重启
+	H2C               bool          // 是否在非 TLS 监听上同时提供 HTTP/2 明文(h2c)
+
+	// keys 配置文件中存在的超时、限制与开关的 key，SetAppConfig 只设置这些值
+	//	为 nil 时（不是由 GetAppConfig 读取的配置）只设置非零值
+	keys map[string]bool
 }
 
 // GetAppConfig 获取配置文件中的信息
@@ -24,7 +44,7 @@ type AppConfig struct {
 //  当环境变量 APP_RUN_MODE ="prod"时，使用 conf/prod.app.conf
 //  没有此环境变量时，使用conf/app.conf
 func GetAppConfig() *AppConfig {
-	return &AppConfig{
+	app := &AppConfig{
 		AppName:       config.DefaultString("app_name", "gow"),
 		RunMode:       config.DefaultString("run_mode", "dev"),
 		HTTPAddr:      config.DefaultString("http_addr", ":8080"),
@@ -34,6 +54,59 @@ func GetAppConfig() *AppConfig {
 		TemplateRight: config.DefaultString("template_right", "}}"),
 		SessionOn:     config.DefaultBool("session_on", false),
 		GzipOn:        config.DefaultBool("gzip_on", false),
+
+		keys: make(map[string]bool),
+	}
+	app.ReadTimeout = app.duration("read_timeout", 0)
+	app.ReadHeaderTimeout = app.duration("read_header_timeout", defaultReadHeaderTimeout)
+	app.WriteTimeout = app.duration("write_timeout", 0)
+	app.IdleTimeout = app.duration("idle_timeout", defaultIdleTimeout)
+	app.ShutdownTimeout = app.duration("shutdown_timeout", defaultShutdownTimeout)
+	app.MaxHeaderBytes = http.DefaultMaxHeaderBytes
+	if v, err := config.GetInt("max_header_bytes"); app.has("max_header_bytes", err) {
+		app.MaxHeaderBytes = v
 	}
+	if v, err := config.GetBool("hot_restart"); app.has("hot_restart", err) {
+		app.HotRestart = v
+	}
+	if v, err := config.GetBool("h2c"); app.has("h2c", err) {
+		app.H2C = v
+	}
+	return app
+}
 
+// duration 读取时间配置，如 10s、1m30s，纯数字为秒，没有配置或格式错误时返回 def
+func (app *AppConfig) duration(key string, def time.Duration) time.Duration {
+	v := config.GetString(key)
+	n, err := strconv.Atoi(v)
+	d := time.Duration(n) * time.Second
+	if err != nil {
+		d, err = time.ParseDuration(v)
+	}
+	if !app.has(key, err) {
+		return def
+	}
+	return d
+}
+
+// has 记录存在且格式正确的 key，格式错误时输出错误日志
+func (app *AppConfig) has(key string, err error) bool {
+	v := config.GetString(key)
+	if v == "" {
+		return false
+	}
+	if err != nil {
+		logy.Errorf("[gow] invalid config %s = %s: %v", key, v, err)
+		return false
+	}
+	app.keys[key] = true
+	return true
+}
+
+// applies 报告 SetAppConfig 是否设置 key 的值，nonzero 为值是否非零
+func (app *AppConfig) applies(key string, nonzero bool) bool {
+	if app.keys == nil {
+		return nonzero
+	}
+	return app.keys[key]
 }
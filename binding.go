@@ -0,0 +1,541 @@
+/*
+struct binding
+	type UserRequest struct {
+		UID    int64                 `uri:"uid"`
+		Token  string                `header:"X-Token"`
+		Page   int                   `form:"page,default=1" json:"page"`
+		Tags   []string              `form:"tag" json:"tags"`
+		Start  time.Time             `form:"start" time_format:"2006-01-02"`
+		Avatar *multipart.FileHeader `form:"avatar"`
+	}
+	var req UserRequest
+	if err := c.ShouldBind(&req); err != nil {
+		c.DataJSON(1, err.Error())
+		return
+	}
+ShouldBind decodes the body by Content-Type, then fills the uri and header tags,
+Bind also aborts the request with 400 when it fails.
+all the binding methods validate the struct by its validate tags after decoding, see validate.go
+*/
+
+package gow
+
+import (
+	"encoding"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v2"
+)
+
+// BindError is returned when a value can not be bound to its field
+type BindError struct {
+	Field string
+	Value string
+	Err   error
+}
+
+func (e *BindError) Error() string {
+	return fmt.Sprintf("bind %s=%q: %v", e.Field, e.Value, e.Err)
+}
+
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+var (
+	errBindPointer   = errors.New("bind needs a non-nil pointer to a struct")
+	errBindEmptyBody = errors.New("request body is empty")
+
+	durationType        = reflect.TypeOf(time.Duration(0))
+	fileHeaderType      = reflect.TypeOf(multipart.FileHeader{})
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// ContentType returns the Content-Type header of the request without its params
+func (c *Context) ContentType() string {
+	return filterFlags(c.GetHeader("Content-Type"))
+}
+
+// Bind is like ShouldBind, it also aborts the request with 400 and
+// adds the error to c.Errors as ErrorTypeBind when binding fails
+func (c *Context) Bind(obj interface{}) error {
+	if err := c.ShouldBind(obj); err != nil {
+		if errors.Is(err, ErrBodyTooLarge) {
+			// 413 has been written when the body was read
+			c.Error(err).SetType(ErrorTypeBind)
+			return err
+		}
+		c.AbortWithError(http.StatusBadRequest, err).SetType(ErrorTypeBind)
+		return err
+	}
+	return nil
+}
+
+// ShouldBind binds the body by Content-Type, json, xml, yaml or form,
+// a request without those content types is bound from the query string and form.
+// The uri and header tags are bound after the body.
+func (c *Context) ShouldBind(obj interface{}) error {
+	var err error
+	switch c.ContentType() {
+	case "application/json":
+		err = c.bindJSON(obj)
+	case "application/xml", "text/xml":
+		err = c.bindXML(obj)
+	case "application/x-yaml", "application/yaml", "text/yaml":
+		err = c.bindYAML(obj)
+	default:
+		err = c.bindForm(obj)
+	}
+	if err != nil {
+		return err
+	}
+	// a body can be decoded to a map, it has no uri and header tags
+	if !isStructPointer(obj) {
+		return nil
+	}
+	if err := c.bindUri(obj); err != nil {
+		return err
+	}
+	if err := c.bindHeader(obj); err != nil {
+		return err
+	}
+	return c.Validate(obj)
+}
+
+// ShouldBindJSON binds the json body
+func (c *Context) ShouldBindJSON(obj interface{}) error {
+	return c.validateBound(obj, c.bindJSON(obj))
+}
+
+// ShouldBindXML binds the xml body
+func (c *Context) ShouldBindXML(obj interface{}) error {
+	return c.validateBound(obj, c.bindXML(obj))
+}
+
+// ShouldBindYAML binds the yaml body
+func (c *Context) ShouldBindYAML(obj interface{}) error {
+	return c.validateBound(obj, c.bindYAML(obj))
+}
+
+// ShouldBindQuery binds the query string by the form tags
+func (c *Context) ShouldBindQuery(obj interface{}) error {
+	return c.validateBound(obj, c.bindQuery(obj))
+}
+
+// ShouldBindForm binds the query string, the form and the multipart form by the form tags,
+// the uploaded files are bound to *multipart.FileHeader and []*multipart.FileHeader fields
+func (c *Context) ShouldBindForm(obj interface{}) error {
+	return c.validateBound(obj, c.bindForm(obj))
+}
+
+// ShouldBindUri binds the route params by the uri tags
+func (c *Context) ShouldBindUri(obj interface{}) error {
+	return c.validateBound(obj, c.bindUri(obj))
+}
+
+// ShouldBindHeader binds the request headers by the header tags
+func (c *Context) ShouldBindHeader(obj interface{}) error {
+	return c.validateBound(obj, c.bindHeader(obj))
+}
+
+// validateBound validates obj when it is bound without error
+func (c *Context) validateBound(obj interface{}, err error) error {
+	if err != nil {
+		return err
+	}
+	return c.Validate(obj)
+}
+
+func (c *Context) bindJSON(obj interface{}) error {
+	return bodyDecodeError(json.NewDecoder(c.bodyStream()).Decode(obj))
+}
+
+func (c *Context) bindXML(obj interface{}) error {
+	return bodyDecodeError(xml.NewDecoder(c.bodyStream()).Decode(obj))
+}
+
+func (c *Context) bindYAML(obj interface{}) error {
+	return bodyDecodeError(yaml.NewDecoder(c.bodyStream()).Decode(obj))
+}
+
+// bodyDecodeError report an empty body as errBindEmptyBody
+func bodyDecodeError(err error) error {
+	if err == io.EOF {
+		return errBindEmptyBody
+	}
+	return err
+}
+
+func (c *Context) bindQuery(obj interface{}) error {
+	query := c.Request.URL.Query()
+	b := &binder{tag: "form", values: func(key string) ([]string, bool) {
+		v, ok := query[key]
+		return v, ok
+	}}
+	return b.bind(obj)
+}
+
+func (c *Context) bindForm(obj interface{}) error {
+	if strings.HasPrefix(c.ContentType(), ContentMultipartPOSTForm) {
+		if err := c.Request.ParseMultipartForm(c.engine.MaxMultipartMemory); err != nil {
+			return err
+		}
+	} else if err := c.Request.ParseForm(); err != nil {
+		return err
+	}
+	form := c.Request.Form
+	b := &binder{tag: "form", values: func(key string) ([]string, bool) {
+		v, ok := form[key]
+		return v, ok
+	}}
+	if c.Request.MultipartForm != nil {
+		b.files = c.Request.MultipartForm.File
+	}
+	return b.bind(obj)
+}
+
+func (c *Context) bindUri(obj interface{}) error {
+	b := &binder{tag: "uri", requireTag: true, values: func(key string) ([]string, bool) {
+		v, ok := c.Params.Get(key)
+		return []string{v}, ok
+	}}
+	return b.bind(obj)
+}
+
+func (c *Context) bindHeader(obj interface{}) error {
+	header := c.Request.Header
+	b := &binder{tag: "header", requireTag: true, values: func(key string) ([]string, bool) {
+		v, ok := header[textproto.CanonicalMIMEHeaderKey(key)]
+		return v, ok
+	}}
+	return b.bind(obj)
+}
+
+// binder fills struct fields by a tag.
+//	`form:"page"`            the key is page
+//	`form:"page,default=1"`  1 is used when page is missing
+//	`form:"-"`               ignored
+// a field without the tag uses its field name, unless requireTag is set.
+// nested structs are flattened, a named one prefixes its keys, like user.name,
+// a nil pointer to a struct is only allocated when the request has a key of its fields.
+// a struct nested in itself, like Next *Node in Node, is not bound again.
+type binder struct {
+	tag        string
+	requireTag bool
+	values     func(key string) ([]string, bool)
+	files      map[string][]*multipart.FileHeader
+
+	// binding holds the struct types on the path of bindStruct
+	binding map[reflect.Type]bool
+}
+
+func (b *binder) bind(obj interface{}) error {
+	if !isStructPointer(obj) {
+		return errBindPointer
+	}
+	_, err := b.bindStruct(reflect.ValueOf(obj).Elem(), "")
+	return err
+}
+
+func isStructPointer(obj interface{}) bool {
+	v := reflect.ValueOf(obj)
+	return v.Kind() == reflect.Ptr && !v.IsNil() && v.Elem().Kind() == reflect.Struct
+}
+
+// bindStruct binds the fields of v, it reports whether any field is set from the request,
+// the defaults are not counted
+func (b *binder) bindStruct(v reflect.Value, prefix string) (bool, error) {
+	var set bool
+	t := v.Type()
+	if b.binding == nil {
+		b.binding = make(map[reflect.Type]bool)
+	}
+	b.binding[t] = true
+	defer delete(b.binding, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		fv := v.Field(i)
+		tag := field.Tag.Get(b.tag)
+		if tag == "-" || !fv.CanSet() {
+			continue
+		}
+		name, opts := tag, ""
+		if j := strings.IndexByte(tag, ','); j >= 0 {
+			name, opts = tag[:j], tag[j+1:]
+		}
+
+		if ft := indirectType(field.Type); ft.Kind() == reflect.Struct && !isBindLeaf(ft) {
+			if b.binding[ft] {
+				continue
+			}
+			nested := prefix
+			if name != "" {
+				nested = prefix + name + "."
+			}
+			target := fv
+			if field.Type.Kind() == reflect.Ptr {
+				target = reflect.New(ft).Elem()
+				if !fv.IsNil() {
+					target = fv.Elem()
+				}
+			}
+			ok, err := b.bindStruct(target, nested)
+			if err != nil {
+				return set, err
+			}
+			if ok && field.Type.Kind() == reflect.Ptr && fv.IsNil() {
+				fv.Set(target.Addr())
+			}
+			set = set || ok
+			continue
+		}
+
+		if name == "" {
+			if b.requireTag {
+				continue
+			}
+			name = field.Name
+		}
+		key := prefix + name
+
+		if ok, err := b.bindFiles(fv, key); ok || err != nil {
+			set = set || ok
+			if err != nil {
+				return set, err
+			}
+			continue
+		}
+
+		values, ok := b.values(key)
+		ok = ok && len(values) > 0
+		if !ok {
+			def, has := bindOption(opts, "default")
+			if !has {
+				continue
+			}
+			values = []string{def}
+		}
+		if err := setBindField(fv, field, values); err != nil {
+			return set, &BindError{Field: key, Value: strings.Join(values, ","), Err: err}
+		}
+		set = set || ok
+	}
+	return set, nil
+}
+
+// bindFiles binds uploaded files to *multipart.FileHeader, multipart.FileHeader and slices of them,
+// it reports false for the other types
+func (b *binder) bindFiles(v reflect.Value, key string) (bool, error) {
+	t := v.Type()
+	slice := t.Kind() == reflect.Slice
+	if slice {
+		t = t.Elem()
+	}
+	if indirectType(t) != fileHeaderType {
+		return false, nil
+	}
+	files := b.files[key]
+	if len(files) == 0 {
+		return false, nil
+	}
+	if !slice {
+		files = files[:1]
+	}
+	values := reflect.MakeSlice(reflect.SliceOf(t), len(files), len(files))
+	for i, file := range files {
+		if t.Kind() == reflect.Ptr {
+			values.Index(i).Set(reflect.ValueOf(file))
+		} else {
+			values.Index(i).Set(reflect.ValueOf(*file))
+		}
+	}
+	if slice {
+		v.Set(values)
+	} else {
+		v.Set(values.Index(0))
+	}
+	return true, nil
+}
+
+// setBindField sets v, a slice takes all the values, the other types the first one
+func setBindField(v reflect.Value, field reflect.StructField, values []string) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			v.SetBytes([]byte(values[0]))
+			return nil
+		}
+		slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, s := range values {
+			if err := setBindValue(slice.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	case reflect.Array:
+		if len(values) != v.Len() {
+			return fmt.Errorf("%d values for an array of %d", len(values), v.Len())
+		}
+		for i, s := range values {
+			if err := setBindValue(v.Index(i), field, s); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return setBindValue(v, field, values[0])
+}
+
+// setBindValue converts s to the type of v
+func setBindValue(v reflect.Value, field reflect.StructField, s string) error {
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setBindValue(p.Elem(), field, s); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		t, err := parseBindTime(field, s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	if v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerType) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+		return nil
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(s))
+		return nil
+	}
+	// an empty value is the zero value
+	if s == "" {
+		v.Set(reflect.Zero(v.Type()))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// parseBindTime parses s by the time tags of field
+//	time_format:"2006-01-02"   layout, RFC3339 by default, or unix, unixmilli and unixnano
+//	time_location:"Asia/Shanghai"
+//	time_utc:"1"
+func parseBindTime(field reflect.StructField, s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	layout := field.Tag.Get("time_format")
+	switch layout {
+	case "":
+		layout = time.RFC3339
+	case "unix", "unixmilli", "unixnano":
+		i, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return time.Time{}, err
+		}
+		switch layout {
+		case "unix":
+			return time.Unix(i, 0), nil
+		case "unixmilli":
+			return time.Unix(0, i*int64(time.Millisecond)), nil
+		default:
+			return time.Unix(0, i), nil
+		}
+	}
+
+	loc := time.Local
+	if utc, _ := strconv.ParseBool(field.Tag.Get("time_utc")); utc {
+		loc = time.UTC
+	}
+	if name := field.Tag.Get("time_location"); name != "" {
+		l, err := time.LoadLocation(name)
+		if err != nil {
+			return time.Time{}, err
+		}
+		loc = l
+	}
+	return time.ParseInLocation(layout, s, loc)
+}
+
+// bindOption returns the value of name=value in the tag options
+func bindOption(opts, name string) (string, bool) {
+	for _, opt := range strings.Split(opts, ",") {
+		if strings.HasPrefix(opt, name+"=") {
+			return opt[len(name)+1:], true
+		}
+	}
+	return "", false
+}
+
+// isBindLeaf reports whether a struct type is bound as a single value
+func isBindLeaf(t reflect.Type) bool {
+	return t == timeType || t == fileHeaderType || reflect.PtrTo(t).Implements(textUnmarshalerType)
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
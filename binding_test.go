@@ -0,0 +1,175 @@
+package gow
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindTestAddress struct {
+	City string `form:"city" json:"city"`
+}
+
+type bindTestRequest struct {
+	UID      int64                   `uri:"uid" json:"-"`
+	Token    string                  `header:"X-Token" json:"-"`
+	Page     int                     `form:"page,default=1" json:"page"`
+	Tags     []string                `form:"tag" json:"tags"`
+	Score    *float64                `form:"score" json:"score"`
+	Start    time.Time               `form:"start" time_format:"2006-01-02" time_utc:"1" json:"start"`
+	Timeout  time.Duration           `form:"timeout" json:"-"`
+	Address  *bindTestAddress        `form:"addr" json:"addr"`
+	Avatar   *multipart.FileHeader   `form:"avatar" json:"-"`
+	Photos   []*multipart.FileHeader `form:"photo" json:"-"`
+	Ignored  string                  `form:"-" json:"-"`
+	Nickname string                  `json:"nickname"`
+}
+
+func newBindTestEngine(bind func(c *Context, req *bindTestRequest) error) (*Engine, *bindTestRequest, *error) {
+	r := New()
+	req := new(bindTestRequest)
+	var err error
+	r.Any("/user/{uid:int}", func(c *Context) {
+		*req = bindTestRequest{}
+		err = bind(c, req)
+	})
+	return r, req, &err
+}
+
+func TestContextShouldBindForm(t *testing.T) {
+	r, req, err := newBindTestEngine(func(c *Context, req *bindTestRequest) error {
+		return c.ShouldBind(req)
+	})
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	mw.WriteField("tag", "go")
+	mw.WriteField("tag", "web")
+	mw.WriteField("addr.city", "chengdu")
+	mw.WriteField("Ignored", "x")
+	fw, _ := mw.CreateFormFile("avatar", "a.png")
+	fw.Write([]byte("png"))
+	for _, name := range []string{"1.jpg", "2.jpg"} {
+		fw, _ = mw.CreateFormFile("photo", name)
+		fw.Write([]byte("jpg"))
+	}
+	mw.Close()
+
+	hr := httptest.NewRequest("POST", "/user/12?score=9.5&start=2021-01-02&timeout=3s&Nickname=sam", &body)
+	hr.Header.Set("Content-Type", mw.FormDataContentType())
+	hr.Header.Set("X-Token", "abc")
+	r.ServeHTTP(httptest.NewRecorder(), hr)
+
+	if *err != nil {
+		t.Fatalf("bind: %v", *err)
+	}
+	if req.UID != 12 || req.Token != "abc" || req.Page != 1 || req.Nickname != "sam" || req.Ignored != "" {
+		t.Errorf("scalars = %+v", req)
+	}
+	if len(req.Tags) != 2 || req.Tags[1] != "web" {
+		t.Errorf("tags = %v", req.Tags)
+	}
+	if req.Score == nil || *req.Score != 9.5 {
+		t.Errorf("score = %v", req.Score)
+	}
+	if !req.Start.Equal(time.Date(2021, 1, 2, 0, 0, 0, 0, time.UTC)) || req.Timeout != 3*time.Second {
+		t.Errorf("start = %v, timeout = %v", req.Start, req.Timeout)
+	}
+	if req.Address == nil || req.Address.City != "chengdu" {
+		t.Errorf("address = %+v", req.Address)
+	}
+	if req.Avatar == nil || req.Avatar.Filename != "a.png" || len(req.Photos) != 2 {
+		t.Errorf("avatar = %v, photos = %d", req.Avatar, len(req.Photos))
+	}
+}
+
+func TestContextShouldBindBody(t *testing.T) {
+	r, req, err := newBindTestEngine(func(c *Context, req *bindTestRequest) error {
+		return c.ShouldBind(req)
+	})
+
+	tests := []struct {
+		contentType string
+		body        string
+	}{
+		{"application/json; charset=utf-8", `{"page":3,"tags":["go"],"nickname":"sam","addr":{"city":"chengdu"}}`},
+		{"application/x-yaml", "page: 3\ntags: [go]\nnickname: sam\naddress:\n  city: chengdu\n"},
+	}
+	for _, tt := range tests {
+		hr := httptest.NewRequest("POST", "/user/7", strings.NewReader(tt.body))
+		hr.Header.Set("Content-Type", tt.contentType)
+		hr.Header.Set("X-Token", "abc")
+		r.ServeHTTP(httptest.NewRecorder(), hr)
+		if *err != nil {
+			t.Errorf("%s: %v", tt.contentType, *err)
+			continue
+		}
+		if req.UID != 7 || req.Token != "abc" || req.Page != 3 || len(req.Tags) != 1 ||
+			req.Nickname != "sam" || req.Address == nil || req.Address.City != "chengdu" {
+			t.Errorf("%s: %+v", tt.contentType, req)
+		}
+	}
+}
+
+func TestContextBindError(t *testing.T) {
+	r, _, err := newBindTestEngine(func(c *Context, req *bindTestRequest) error {
+		return c.Bind(req)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/user/1?page=abc", nil))
+	bindErr, ok := (*err).(*BindError)
+	if !ok || bindErr.Field != "page" || bindErr.Value != "abc" {
+		t.Errorf("err = %v", *err)
+	}
+	if w.Code != 400 {
+		t.Errorf("code = %d, want 400", w.Code)
+	}
+
+	w = httptest.NewRecorder()
+	hr := httptest.NewRequest("POST", "/user/1", nil)
+	hr.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, hr)
+	if *err != errBindEmptyBody {
+		t.Errorf("empty body err = %v", *err)
+	}
+
+	c := &Context{}
+	if e := c.ShouldBindUri(bindTestRequest{}); e != errBindPointer {
+		t.Errorf("non pointer err = %v", e)
+	}
+}
+
+type bindTestNode struct {
+	Name   string        `form:"name"`
+	Next   *bindTestNode `form:"next"`
+	Parent struct {
+		Node *bindTestNode `form:"node"`
+	} `form:"parent"`
+	Option *struct {
+		Size int `form:"size,default=10"`
+	} `form:"option"`
+}
+
+func TestContextBindNested(t *testing.T) {
+	c := &Context{Request: httptest.NewRequest("GET", "/?name=a&next.name=b", nil)}
+	var node bindTestNode
+	if err := c.bindQuery(&node); err != nil {
+		t.Fatal(err)
+	}
+	// a struct nested in itself is skipped, a nil pointer with defaults only is not allocated
+	if node.Name != "a" || node.Next != nil || node.Parent.Node != nil || node.Option != nil {
+		t.Errorf("node = %+v", node)
+	}
+
+	c.Request = httptest.NewRequest("GET", "/?option.size=20", nil)
+	if err := c.bindQuery(&node); err != nil {
+		t.Fatal(err)
+	}
+	if node.Option == nil || node.Option.Size != 20 {
+		t.Errorf("option = %+v", node.Option)
+	}
+}
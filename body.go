@@ -0,0 +1,158 @@
+package gow
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+)
+
+// ErrBodyTooLarge the request body is larger than MaxBodyBytes
+var ErrBodyTooLarge = errors.New("http: request body too large")
+
+// MaxBodyBytes limit the request body of the routes, it overrides Engine.MaxBodyBytes,
+// 0 removes the limit
+//	r.POST("/video", gow.MaxBodyBytes(500<<20), upload)
+//	api := r.Group("/api", gow.MaxBodyBytes(1<<20))
+func MaxBodyBytes(n int64) HandlerFunc {
+	return func(c *Context) {
+		if n > 0 && c.Request.ContentLength > n {
+			c.bodyTooLarge()
+			return
+		}
+		c.limitBody(n)
+	}
+}
+
+// BodyReader return a reader of the request body from the start,
+// the bytes read are cached so middleware can peek the body and the handler still reads all of it
+//	buf := make([]byte, 512)
+//	n, _ := io.ReadFull(c.BodyReader(), buf)
+func (c *Context) BodyReader() io.Reader {
+	if c.Request.Body == nil {
+		return http.NoBody
+	}
+	if c.bodyCache == nil {
+		c.bodyCache = &bodyCache{src: c.Request.Body}
+	}
+	c.Request.Body = ioutil.NopCloser(&bodyReplay{cache: c.bodyCache})
+	return &bodyReplay{cache: c.bodyCache}
+}
+
+// bodyStream return the body for decoding, the body is read straight from the connection
+// unless BodyReader has cached it
+func (c *Context) bodyStream() io.Reader {
+	if c.Request.Body == nil {
+		return http.NoBody
+	}
+	if c.bodyCache != nil {
+		return &bodyReplay{cache: c.bodyCache}
+	}
+	return c.Request.Body
+}
+
+// limitBody set the max size of the body, n <= 0 means no limit
+func (c *Context) limitBody(n int64) {
+	if c.bodyLimit != nil {
+		c.bodyLimit.limit = n
+		return
+	}
+	if n <= 0 || c.Request.Body == nil {
+		return
+	}
+	c.bodyLimit = &limitedBody{c: c, limit: n}
+	if c.bodyCache != nil {
+		c.bodyLimit.rc = ioutil.NopCloser(c.bodyCache.src)
+		c.bodyLimit.n = int64(len(c.bodyCache.buf))
+		c.bodyCache.src = c.bodyLimit
+		return
+	}
+	c.bodyLimit.rc = c.Request.Body
+	c.Request.Body = c.bodyLimit
+}
+
+// bodyTooLarge answer 413 unless the response has been written
+func (c *Context) bodyTooLarge() {
+	if c.Writer.Written() {
+		return
+	}
+	c.Header("Connection", "close")
+	c.AbortWithError(http.StatusRequestEntityTooLarge, ErrBodyTooLarge)
+}
+
+// limitedBody like http.MaxBytesReader, it answers 413 when the limit is exceeded,
+// the limit can be changed until the body is read
+type limitedBody struct {
+	c     *Context
+	rc    io.ReadCloser
+	limit int64
+	n     int64
+	err   error
+}
+
+func (b *limitedBody) Read(p []byte) (int, error) {
+	if b.err != nil {
+		return 0, b.err
+	}
+	if b.limit <= 0 {
+		n, err := b.rc.Read(p)
+		b.n += int64(n)
+		return n, err
+	}
+	if b.n == 0 && b.c.Request.ContentLength > b.limit {
+		return 0, b.tooLarge()
+	}
+	if remain := b.limit - b.n + 1; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	n, err := b.rc.Read(p)
+	b.n += int64(n)
+	if b.n > b.limit {
+		n -= int(b.n - b.limit)
+		b.n = b.limit
+		return n, b.tooLarge()
+	}
+	return n, err
+}
+
+func (b *limitedBody) Close() error {
+	return b.rc.Close()
+}
+
+func (b *limitedBody) tooLarge() error {
+	b.err = ErrBodyTooLarge
+	b.c.bodyTooLarge()
+	return b.err
+}
+
+// bodyCache the bytes of the body read so far
+type bodyCache struct {
+	src io.Reader
+	buf []byte
+	err error
+}
+
+// bodyReplay read the cached bytes first, then read on from the source and cache the bytes
+type bodyReplay struct {
+	cache *bodyCache
+	off   int
+}
+
+func (r *bodyReplay) Read(p []byte) (int, error) {
+	cache := r.cache
+	if r.off < len(cache.buf) {
+		n := copy(p, cache.buf[r.off:])
+		r.off += n
+		return n, nil
+	}
+	if cache.err != nil {
+		return 0, cache.err
+	}
+	n, err := cache.src.Read(p)
+	cache.buf = append(cache.buf, p[:n]...)
+	r.off += n
+	if err != nil {
+		cache.err = err
+	}
+	return n, err
+}
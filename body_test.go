@@ -0,0 +1,83 @@
+package gow
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestEngineMaxBodyBytes(t *testing.T) {
+	r := New()
+	r.MaxBodyBytes = 16
+	var bindErr error
+	bind := func(c *Context) {
+		var v H
+		if bindErr = c.ShouldBindJSON(&v); bindErr != nil {
+			c.DataJSON(bindErr)
+			return
+		}
+		c.String("ok")
+	}
+	r.POST("/small", bind)
+	r.POST("/large", MaxBodyBytes(64), bind)
+	r.POST("/tiny", MaxBodyBytes(4), bind)
+
+	body := `{"name":"gow","age":1}`
+	tests := []struct {
+		path    string
+		chunked bool
+		code    int
+		err     error
+	}{
+		{"/small", false, 413, ErrBodyTooLarge},
+		{"/small", true, 413, ErrBodyTooLarge},
+		{"/large", false, 200, nil},
+		{"/large", true, 200, nil},
+		{"/tiny", false, 413, nil},
+	}
+	for _, tt := range tests {
+		bindErr = nil
+		req := httptest.NewRequest("POST", tt.path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.chunked {
+			req.ContentLength = -1
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || !errors.Is(bindErr, tt.err) && bindErr != tt.err {
+			t.Errorf("%s chunked=%v: code = %d, err = %v, want %d, %v", tt.path, tt.chunked, w.Code, bindErr, tt.code, tt.err)
+		}
+	}
+}
+
+func TestContextBodyReader(t *testing.T) {
+	r := New()
+	r.Use(func(c *Context) {
+		buf := make([]byte, 8)
+		io.ReadFull(c.BodyReader(), buf)
+		c.SetKey("peek", string(buf))
+		c.Next()
+	})
+	r.POST("/user", func(c *Context) {
+		var v struct {
+			Name string `json:"name"`
+		}
+		if err := c.ShouldBindJSON(&v); err != nil {
+			t.Fatal(err)
+		}
+		raw, _ := ioutil.ReadAll(c.Request.Body)
+		peek, _ := c.GetKey("peek")
+		c.String(peek.(string) + "|" + v.Name + "|" + string(c.Body()) + "|" + string(raw))
+	})
+
+	body := `{"name":"gow"}`
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("POST", "/user", strings.NewReader(body)))
+	want := `{"name":|gow|` + body + "|" + body
+	if w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+}
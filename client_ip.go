@@ -0,0 +1,168 @@
+package gow
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// defaultTrustedProxies loopback, a proxy on the same host
+var defaultTrustedProxies = []string{"127.0.0.0/8", "::1/128"}
+
+// PrivateNetworks the loopback and private networks, where the proxies of a k8s cluster usually are.
+// Any host of these networks can set the client ip when they are trusted.
+//	r.SetTrustedProxies(gow.PrivateNetworks)
+var PrivateNetworks = []string{
+	"127.0.0.0/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7",
+}
+
+// defaultRemoteIPHeaders the headers carrying the client ip, in order
+var defaultRemoteIPHeaders = []string{"Forwarded", "X-Forwarded-For", "X-Real-IP"}
+
+// SetTrustedProxies set the networks of the proxies whose RemoteIPHeaders are trusted,
+// the default is loopback, PrivateNetworks trusts the private networks too, nil trusts no proxy
+//	r.SetTrustedProxies([]string{"10.0.0.0/8", "192.168.1.2"})
+func (engine *Engine) SetTrustedProxies(proxies []string) error {
+	cidrs, err := parseCIDRs(proxies)
+	if err != nil {
+		return err
+	}
+	engine.trustedCIDRs = cidrs
+	return nil
+}
+
+// isTrustedProxy report whether the ip is a trusted proxy
+func (engine *Engine) isTrustedProxy(ip net.IP) bool {
+	for _, cidr := range engine.trustedCIDRs {
+		if cidr.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// fromTrustedProxy report whether the peer of the request is a trusted proxy,
+// a peer without ip is trusted only when the request comes from a unix socket
+func (c *Context) fromTrustedProxy() bool {
+	if ip := net.ParseIP(c.RemoteIP()); ip != nil {
+		return c.engine.isTrustedProxy(ip)
+	}
+	_, ok := c.Request.Context().Value(http.LocalAddrContextKey).(*net.UnixAddr)
+	return ok
+}
+
+// ClientIP return the client ip
+//	the RemoteIPHeaders are used only when ForwardedByClientIP is on and the request comes from a trusted proxy,
+//	the addresses of trusted proxies in the headers are skipped from right to left,
+//	so a client can not spoof its ip by sending the headers itself
+func (c *Context) ClientIP() string {
+	remoteIP := c.RemoteIP()
+	engine := c.engine
+	if engine == nil || !engine.ForwardedByClientIP || !c.fromTrustedProxy() {
+		return remoteIP
+	}
+	for _, name := range engine.RemoteIPHeaders {
+		if ip, ok := engine.ipFromHeader(name, c.Request.Header); ok {
+			return ip
+		}
+	}
+	return remoteIP
+}
+
+// GetIP return the client ip, see ClientIP
+func (c *Context) GetIP() string {
+	return c.ClientIP()
+}
+
+// RemoteIP return the ip of the peer of the connection, IPv6 addresses are supported,
+// it returns "" for unix sockets
+func (c *Context) RemoteIP() string {
+	addr := strings.TrimSpace(c.Request.RemoteAddr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	if i := strings.IndexByte(addr, '%'); i >= 0 {
+		addr = addr[:i]
+	}
+	if net.ParseIP(addr) == nil {
+		return ""
+	}
+	return addr
+}
+
+// ipFromHeader return the client ip in the header
+func (engine *Engine) ipFromHeader(name string, header http.Header) (string, bool) {
+	values := header.Values(name)
+	if len(values) == 0 {
+		return "", false
+	}
+	var ips []string
+	switch http.CanonicalHeaderKey(name) {
+	case "Forwarded":
+		ips = parseForwarded(values)
+	default:
+		for _, v := range values {
+			for _, ip := range strings.Split(v, ",") {
+				ips = append(ips, strings.TrimSpace(ip))
+			}
+		}
+	}
+
+	// the rightmost address that is not a trusted proxy is the client
+	for i := len(ips) - 1; i >= 0; i-- {
+		ip := net.ParseIP(ips[i])
+		if ip == nil {
+			return "", false
+		}
+		if i == 0 || !engine.isTrustedProxy(ip) {
+			return ip.String(), true
+		}
+	}
+	return "", false
+}
+
+// parseForwarded return the for= addresses of the RFC 7239 Forwarded header
+//	Forwarded: for=192.0.2.60;proto=http;by=203.0.113.43, for="[2001:db8:cafe::17]:4711"
+//	an obfuscated or unknown address is returned as is, and it makes the header unusable
+func parseForwarded(values []string) []string {
+	var ips []string
+	for _, v := range values {
+		for _, element := range strings.Split(v, ",") {
+			for _, pair := range strings.Split(element, ";") {
+				kv := strings.SplitN(strings.TrimSpace(pair), "=", 2)
+				if len(kv) != 2 || !strings.EqualFold(kv[0], "for") {
+					continue
+				}
+				node := strings.Trim(kv[1], `"`)
+				if host, _, err := net.SplitHostPort(node); err == nil {
+					node = host
+				}
+				ips = append(ips, strings.Trim(node, "[]"))
+			}
+		}
+	}
+	return ips
+}
+
+func parseCIDRs(networks []string) ([]*net.IPNet, error) {
+	cidrs := make([]*net.IPNet, 0, len(networks))
+	for _, network := range networks {
+		if !strings.Contains(network, "/") {
+			ip := net.ParseIP(network)
+			if ip == nil {
+				return nil, &net.ParseError{Type: "IP address", Text: network}
+			}
+			if ip4 := ip.To4(); ip4 != nil {
+				network += "/32"
+			} else {
+				network += "/128"
+			}
+		}
+		_, cidr, err := net.ParseCIDR(network)
+		if err != nil {
+			return nil, err
+		}
+		cidrs = append(cidrs, cidr)
+	}
+	return cidrs, nil
+}
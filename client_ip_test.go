@@ -0,0 +1,92 @@
+package gow
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type clientIPTest struct {
+	remoteAddr string
+	header     []string
+	ip         string
+}
+
+func testClientIP(t *testing.T, r *Engine, tests []clientIPTest) {
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/ip", nil)
+		req.RemoteAddr = tt.remoteAddr
+		for i := 0; i < len(tt.header); i += 2 {
+			req.Header.Set(tt.header[i], tt.header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tt.ip {
+			t.Errorf("%s %v = %q, want %q", tt.remoteAddr, tt.header, w.Body.String(), tt.ip)
+		}
+	}
+}
+
+func TestContextClientIP(t *testing.T) {
+	r := New()
+	r.GET("/ip", func(c *Context) {
+		c.String(c.ClientIP())
+	})
+
+	testClientIP(t, r, []clientIPTest{
+		{"203.0.113.7:1234", nil, "203.0.113.7"},
+		{"[2001:db8::1]:1234", nil, "2001:db8::1"},
+		{"[fe80::1%eth0]:1234", nil, "fe80::1"},
+		{"@", nil, ""},
+		// headers from an untrusted client are ignored
+		{"203.0.113.7:1234", []string{"X-Forwarded-For", "1.2.3.4"}, "203.0.113.7"},
+		{"203.0.113.7:1234", []string{"X-Real-IP", "1.2.3.4"}, "203.0.113.7"},
+		// only loopback is trusted by default
+		{"10.0.0.2:1234", []string{"X-Forwarded-For", "198.51.100.9"}, "10.0.0.2"},
+		// a trusted proxy appends the address of its peer, spoofed addresses on the left are skipped
+		{"127.0.0.1:1234", []string{"X-Forwarded-For", "1.2.3.4, 198.51.100.9, 127.0.0.3"}, "198.51.100.9"},
+		{"127.0.0.1:1234", []string{"X-Forwarded-For", "127.0.0.5, 127.0.0.3"}, "127.0.0.5"},
+		{"127.0.0.1:1234", []string{"X-Forwarded-For", "junk"}, "127.0.0.1"},
+		{"127.0.0.1:1234", []string{"X-Real-IP", "198.51.100.9"}, "198.51.100.9"},
+		{"[::1]:1234", []string{"Forwarded", `for=1.2.3.4, for="[2001:db8:cafe::17]:4711";proto=https`}, "2001:db8:cafe::17"},
+		{"[::1]:1234", []string{"Forwarded", "for=_hidden", "X-Forwarded-For", "198.51.100.9"}, "198.51.100.9"},
+		// a peer without ip is trusted only on a unix socket
+		{"@", []string{"X-Forwarded-For", "198.51.100.9"}, ""},
+	})
+
+	req := httptest.NewRequest("GET", "/ip", nil)
+	req.RemoteAddr = "@"
+	req.Header.Set("X-Forwarded-For", "198.51.100.9")
+	req = req.WithContext(context.WithValue(req.Context(), http.LocalAddrContextKey, &net.UnixAddr{Name: "/tmp/gow.sock", Net: "unix"}))
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "198.51.100.9" {
+		t.Errorf("unix socket peer = %q, want 198.51.100.9", w.Body.String())
+	}
+
+	if err := r.SetTrustedProxies(PrivateNetworks); err != nil {
+		t.Fatal(err)
+	}
+	testClientIP(t, r, []clientIPTest{
+		{"10.0.0.2:1234", []string{"X-Forwarded-For", "1.2.3.4, 198.51.100.9, 10.0.0.3"}, "198.51.100.9"},
+		{"10.0.0.2:1234", []string{"X-Forwarded-For", "10.0.0.5, 10.0.0.3"}, "10.0.0.5"},
+		{"[fd00::1]:1234", []string{"X-Real-IP", "198.51.100.9"}, "198.51.100.9"},
+		{"203.0.113.7:1234", []string{"X-Real-IP", "1.2.3.4"}, "203.0.113.7"},
+	})
+
+	if err := r.SetTrustedProxies([]string{"192.168.1.2", "2001:db8::/32"}); err != nil {
+		t.Fatal(err)
+	}
+	r.RemoteIPHeaders = []string{"X-Real-IP"}
+	testClientIP(t, r, []clientIPTest{
+		{"10.0.0.2:1234", []string{"X-Real-IP", "198.51.100.9"}, "10.0.0.2"},
+		{"192.168.1.2:1234", []string{"X-Forwarded-For", "198.51.100.9"}, "192.168.1.2"},
+		{"[2001:db8::1]:1234", []string{"X-Real-IP", "198.51.100.9"}, "198.51.100.9"},
+	})
+
+	if err := r.SetTrustedProxies([]string{"10.0.0.0/33"}); err == nil {
+		t.Error("invalid cidr is accepted")
+	}
+}
@@ -33,6 +33,7 @@ const (
 	ContentMSGPACK2          = "application/msgpack"
 	ContentYAML              = "application/x-yaml; charset=utf-8"
 	ContentDownload          = "application/octet-stream; charset=utf-8"
+	ContentEventStream       = "text/event-stream"
 )
 
 // Context gow context
@@ -46,8 +47,14 @@ type Context struct {
 	index    int8
 	fullPath string
 
-	engine *Engine
-	params *Params
+	engine     *Engine
+	params     *Params
+	paramTypes map[string]*paramType
+	meta       *RouteMeta
+
+	// bodyLimit enforces MaxBodyBytes, bodyCache replays the body read by BodyReader
+	bodyLimit *limitedBody
+	bodyCache *bodyCache
 
 	// This mutex protect Keys map
 	mu sync.RWMutex
@@ -88,6 +95,10 @@ func (c *Context) reset() {
 	c.handlers = nil
 	c.index = -1
 	c.fullPath = ""
+	c.paramTypes = nil
+	c.meta = nil
+	c.bodyLimit = nil
+	c.bodyCache = nil
 	c.Keys = nil
 	c.Errors = c.Errors[0:0]
 	c.Accepted = nil
@@ -228,6 +239,34 @@ func (c *Context) ParamInt64(key string) (int64, error) {
 	return strconv.ParseInt(v, 10, 64)
 }
 
+// ParamValue returns the value of the URL param parsed by its route param type
+//	{id:int64} returns int64, {day:date} returns time.Time
+//	types without a parser return the string value
+func (c *Context) ParamValue(key string) (interface{}, error) {
+	v, ok := c.Params.Get(key)
+	if !ok {
+		return nil, fmt.Errorf("param %s not found", key)
+	}
+	if typ := c.paramTypes[key]; typ != nil && typ.parse != nil {
+		return typ.parse(v)
+	}
+	return v, nil
+}
+
+// ParamTime returns the value of the URL param as time.Time
+//	router.GET("/report/{day:date}", handler)
+//	day, err := c.ParamTime("day")
+func (c *Context) ParamTime(key string) (time.Time, error) {
+	v, err := c.ParamValue(key)
+	if err != nil {
+		return time.Time{}, err
+	}
+	if t, ok := v.(time.Time); ok {
+		return t, nil
+	}
+	return time.Parse(paramDateLayout, c.Param(key))
+}
+
 // UserAgent get useragent
 func (c *Context) UserAgent() string {
 	return c.GetHeader("User-Agent")
@@ -642,17 +681,13 @@ func (c *Context) Redirect(code int, url string) {
 }
 
 // Body request body
+//	the body is cached, c.Request.Body and BodyReader read it again from the start
 func (c *Context) Body() []byte {
 	if c.Request.Body == nil {
 		return []byte{}
 	}
-	var body []byte
-	body, _ = ioutil.ReadAll(c.Request.Body)
-
-	c.Request.Body.Close()
-	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer(body))
-
-	return body
+	io.Copy(ioutil.Discard, c.BodyReader())
+	return c.bodyCache.buf
 }
 
 // Render render html
@@ -805,45 +840,6 @@ func (c *Context) XML(data interface{}) {
 	c.ServerXML(http.StatusOK, data)
 }
 
-// GetIP return k8s Cluster ip
-//	if ip=="" return "10.10.10.2"
-func (c *Context) GetIP() (ip string) {
-	//header传递传递的IP
-	ip = c.GetHeader("ip")
-	if ip == "" {
-		ip = c.GetHeader("X-Original-Forwarded-For")
-	}
-	if ip == "" {
-		ip = c.GetHeader("Remote-Host")
-	}
-	if ip == "" {
-		ip = c.GetHeader("X-Real-IP")
-	}
-	if ip == "" {
-		ip = c.ClientIP()
-	}
-	if ip == "" {
-		ip = "10.10.10.2"
-	}
-
-	ips := strings.Split(ip, ",")
-	if len(ips) > 0 {
-		ip = ips[0]
-	}
-
-	return strings.TrimSpace(ip)
-}
-
-// ClientIP get client ip address
-func (c *Context) ClientIP() (ip string) {
-	addr := c.Request.RemoteAddr
-	str := strings.Split(addr, ":")
-	if len(str) > 1 {
-		ip = str[0]
-	}
-	return
-}
-
 // Status sets the HTTP response code.
 func (c *Context) Status(code int) {
 	c.Writer.WriteHeader(code)
@@ -889,10 +885,41 @@ func (c *Context) FileAttachment(filepath, filename string) {
 }
 
 // Download download data
+//	Content-Length, ETag and Range requests are supported
 func (c *Context) Download(data []byte) {
-	c.Header("Content-Type", ContentDownload)
-	c.Writer.WriteHeader(http.StatusOK)
-	c.Writer.Write(data)
+	c.DataFromReader(http.StatusOK, int64(len(data)), ContentDownload, bytes.NewReader(data), map[string]string{
+		"ETag": etagOf(data),
+	})
+}
+
+// DataFromReader writes size bytes of reader into the body stream with the headers,
+// Range, If-Range and the conditional headers are handled when code is 200, the Last-Modified header is used as the modtime
+//	f, _ := os.Open("video.mp4")
+//	defer f.Close()
+//	fi, _ := f.Stat()
+//	c.DataFromReader(200, fi.Size(), "video/mp4", f, map[string]string{
+//		"Last-Modified": fi.ModTime().UTC().Format(http.TimeFormat),
+//	})
+func (c *Context) DataFromReader(code int, size int64, contentType string, reader io.ReadSeeker, headers map[string]string) {
+	for k, v := range headers {
+		c.Header(k, v)
+	}
+	if contentType != "" {
+		c.Header("Content-Type", contentType)
+	}
+	if code != http.StatusOK {
+		c.Header("Content-Length", strconv.FormatInt(size, 10))
+		c.Status(code)
+		io.CopyN(c.Writer, reader, size)
+		return
+	}
+	modtime, _ := http.ParseTime(c.Writer.Header().Get("Last-Modified"))
+	content, err := newSizedReadSeeker(reader, size)
+	if err != nil {
+		c.ServerString(http.StatusInternalServerError, err.Error())
+		return
+	}
+	http.ServeContent(c.Writer, c.Request, "", modtime, content)
 }
 
 //============private method=============
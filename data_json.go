@@ -58,9 +58,11 @@ func DataPager() HandlerFunc {
 
 // ServerDataJSON json format response
 //	ex:c.ServerDataJSON(401,1,"Unauthorized")
+//	ValidationErrors are written as data, ex:c.ServerDataJSON(200,err)
 func (c *Context) ServerDataJSON(statusCode int, args ...interface{}) {
 	var (
 		err   error
+		verrs ValidationErrors
 		pager *Pager
 		data  interface{}
 		msg   string
@@ -72,6 +74,8 @@ func (c *Context) ServerDataJSON(statusCode int, args ...interface{}) {
 			code = vv
 		case string:
 			msg = vv
+		case ValidationErrors:
+			verrs = vv
 		case error:
 			err = vv
 		case *Pager:
@@ -83,6 +87,18 @@ func (c *Context) ServerDataJSON(statusCode int, args ...interface{}) {
 	if err != nil {
 		debugPrint("[error] %v %v", c.Request.URL.String(), err.Error())
 	}
+	// validation errors are written as data with the validation code and the first message
+	if len(verrs) > 0 {
+		if code == 0 {
+			code = c.engine.ValidationErrorCode
+		}
+		if msg == "" {
+			msg = verrs[0].Message
+		}
+		if data == nil {
+			data = verrs
+		}
+	}
 	if code == 0 && msg == "" {
 		msg = "success"
 	}
@@ -115,9 +131,9 @@ func (c *Context) DataJSON(args ...interface{}) {
 }
 
 // DecodeJSONBody request body to struct or map
+//	the body is decoded from the stream, call c.Body or c.BodyReader first to read it again later
 func (c *Context) DecodeJSONBody(v interface{}) error {
-	body := c.Body()
-	return json.Unmarshal(body, &v)
+	return json.NewDecoder(c.bodyStream()).Decode(&v)
 }
 
 // getPageCount return pagerCount
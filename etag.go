@@ -0,0 +1,250 @@
+package gow
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// etagMaxBuffer responses larger than this are sent without ETag by the ETag middleware
+const etagMaxBuffer = 4 << 20
+
+// ETag middleware buffers the 200 responses of GET and HEAD requests and sets an ETag from the hash of the body,
+// it answers 304 when the ETag matches If-None-Match
+//	streamed responses and responses larger than 4MB are sent as they are
+//	r.Use(gow.ETag())
+func ETag() HandlerFunc {
+	return func(c *Context) {
+		if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+			c.Next()
+			return
+		}
+		w := &etagWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+		if w.passthrough {
+			return
+		}
+
+		header := w.Header()
+		if w.status == http.StatusOK && header.Get("ETag") == "" {
+			header.Set("ETag", etagOf(w.buf.Bytes()))
+		}
+		if w.status == http.StatusOK && c.NotModified() {
+			return
+		}
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(w.buf.Bytes())
+	}
+}
+
+// SetETag set the ETag header, the tag is quoted when it is not
+//	c.SetETag(strconv.FormatInt(article.Version, 10))
+func (c *Context) SetETag(etag string) {
+	if !strings.HasSuffix(etag, `"`) {
+		etag = `"` + etag + `"`
+	}
+	c.Header("ETag", etag)
+}
+
+// SetLastModified set the Last-Modified header
+func (c *Context) SetLastModified(t time.Time) {
+	if t.IsZero() {
+		return
+	}
+	c.Header("Last-Modified", t.UTC().Format(http.TimeFormat))
+}
+
+// NotModified report whether the client cache is fresh by If-None-Match and If-Modified-Since
+// against the ETag and Last-Modified headers of the response, 304 is written when it is fresh
+//	c.SetLastModified(article.UpdatedAt)
+//	if c.NotModified() {
+//		return
+//	}
+//	c.JSON(article)
+func (c *Context) NotModified() bool {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+	header := c.Writer.Header()
+	if inm := c.GetHeader("If-None-Match"); inm != "" {
+		if !etagMatch(inm, header.Get("ETag")) {
+			return false
+		}
+	} else {
+		ims, err := http.ParseTime(c.GetHeader("If-Modified-Since"))
+		if err != nil {
+			return false
+		}
+		modtime, err := http.ParseTime(header.Get("Last-Modified"))
+		if err != nil || modtime.After(ims) {
+			return false
+		}
+	}
+	header.Del("Content-Type")
+	header.Del("Content-Length")
+	c.AbortWithStatus(http.StatusNotModified)
+	return true
+}
+
+// etagMatch weak comparison of If-None-Match and the ETag
+func etagMatch(ifNoneMatch, etag string) bool {
+	if etag == "" {
+		return false
+	}
+	etag = strings.TrimPrefix(etag, "W/")
+	for _, tag := range strings.Split(ifNoneMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// etagOf return a strong ETag of the data
+func etagOf(data []byte) string {
+	sum := sha1.Sum(data)
+	return `"` + strconv.Itoa(len(data)) + "-" + hex.EncodeToString(sum[:10]) + `"`
+}
+
+// etagWriter buffers the response until the handlers return
+type etagWriter struct {
+	ResponseWriter
+	buf         bytes.Buffer
+	status      int
+	passthrough bool
+}
+
+func (w *etagWriter) WriteHeader(code int) {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeader(code)
+		return
+	}
+	if code > 0 {
+		w.status = code
+	}
+}
+
+func (w *etagWriter) WriteHeaderNow() {
+	if w.passthrough {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *etagWriter) Write(data []byte) (int, error) {
+	if w.passthrough {
+		return w.ResponseWriter.Write(data)
+	}
+	if w.buf.Len()+len(data) > etagMaxBuffer {
+		w.pass()
+		return w.ResponseWriter.Write(data)
+	}
+	return w.buf.Write(data)
+}
+
+func (w *etagWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *etagWriter) Status() int {
+	if w.passthrough {
+		return w.ResponseWriter.Status()
+	}
+	return w.status
+}
+
+func (w *etagWriter) Size() int {
+	if w.passthrough {
+		return w.ResponseWriter.Size()
+	}
+	if w.buf.Len() == 0 {
+		return noWritten
+	}
+	return w.buf.Len()
+}
+
+func (w *etagWriter) Written() bool {
+	if w.passthrough {
+		return w.ResponseWriter.Written()
+	}
+	return w.buf.Len() > 0
+}
+
+// Flush stops buffering, the response is streamed
+func (w *etagWriter) Flush() {
+	w.pass()
+	w.ResponseWriter.Flush()
+}
+
+func (w *etagWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.pass()
+	return w.ResponseWriter.Hijack()
+}
+
+// pass write the buffered response and stop buffering
+func (w *etagWriter) pass() {
+	if w.passthrough {
+		return
+	}
+	w.passthrough = true
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.buf.Len() > 0 {
+		w.ResponseWriter.Write(w.buf.Bytes())
+		w.buf.Reset()
+	}
+}
+
+// sizedReadSeeker limits a ReadSeeker to size bytes from its current offset
+type sizedReadSeeker struct {
+	r    io.ReadSeeker
+	base int64
+	size int64
+	off  int64
+}
+
+func newSizedReadSeeker(r io.ReadSeeker, size int64) (*sizedReadSeeker, error) {
+	base, err := r.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, err
+	}
+	return &sizedReadSeeker{r: r, base: base, size: size}, nil
+}
+
+func (s *sizedReadSeeker) Read(p []byte) (int, error) {
+	if s.off >= s.size {
+		return 0, io.EOF
+	}
+	if remain := s.size - s.off; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	n, err := s.r.Read(p)
+	s.off += int64(n)
+	return n, err
+}
+
+func (s *sizedReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		offset += s.off
+	case io.SeekEnd:
+		offset += s.size
+	}
+	if offset < 0 {
+		return 0, errors.New("seek: negative position")
+	}
+	if _, err := s.r.Seek(s.base+offset, io.SeekStart); err != nil {
+		return 0, err
+	}
+	s.off = offset
+	return offset, nil
+}
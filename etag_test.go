@@ -0,0 +1,104 @@
+package gow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestContextDataFromReader(t *testing.T) {
+	modtime := time.Date(2021, 3, 9, 0, 0, 0, 0, time.UTC)
+	r := New()
+	r.GET("/video", func(c *Context) {
+		content := strings.NewReader("xxhello worldyy")
+		content.Seek(2, 0)
+		c.DataFromReader(200, 11, "video/mp4", content, map[string]string{
+			"Last-Modified": modtime.Format(http.TimeFormat),
+			"ETag":          `"v1"`,
+		})
+	})
+	r.GET("/download", func(c *Context) {
+		c.Download([]byte("hello world"))
+	})
+
+	tests := []struct {
+		path   string
+		header []string
+		code   int
+		body   string
+		rng    string
+	}{
+		{"/video", nil, 200, "hello world", ""},
+		{"/video", []string{"Range", "bytes=6-"}, 206, "world", "bytes 6-10/11"},
+		{"/video", []string{"Range", "bytes=0-4", "If-Range", `"v1"`}, 206, "hello", "bytes 0-4/11"},
+		{"/video", []string{"Range", "bytes=0-4", "If-Range", `"v0"`}, 200, "hello world", ""},
+		{"/video", []string{"Range", "bytes=20-"}, 416, "", "bytes */11"},
+		{"/video", []string{"If-None-Match", `"v1"`}, 304, "", ""},
+		{"/video", []string{"If-Modified-Since", modtime.Format(http.TimeFormat)}, 304, "", ""},
+		{"/video", []string{"If-Modified-Since", modtime.Add(-time.Hour).Format(http.TimeFormat)}, 200, "hello world", ""},
+		{"/download", []string{"Range", "bytes=-5"}, 206, "world", "bytes 6-10/11"},
+		{"/download", []string{"If-None-Match", etagOf([]byte("hello world"))}, 304, "", ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		for i := 0; i < len(tt.header); i += 2 {
+			req.Header.Set(tt.header[i], tt.header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || (tt.code != 416 && w.Body.String() != tt.body) || w.Header().Get("Content-Range") != tt.rng {
+			t.Errorf("%s %v = %d %q %q, want %d %q %q", tt.path, tt.header, w.Code, w.Body.String(),
+				w.Header().Get("Content-Range"), tt.code, tt.body, tt.rng)
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	updated := time.Date(2021, 3, 9, 0, 0, 0, 0, time.UTC)
+	r := New()
+	r.Use(ETag())
+	r.GET("/user", func(c *Context) {
+		c.JSON(H{"name": "gow"})
+	})
+	r.GET("/article", func(c *Context) {
+		c.SetLastModified(updated)
+		if c.NotModified() {
+			return
+		}
+		c.String("article")
+	})
+	r.GET("/stream", func(c *Context) {
+		c.SSEvent("tick", 1)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/user", nil))
+	etag := w.Header().Get("ETag")
+	if w.Code != 200 || etag == "" || !strings.Contains(w.Body.String(), "gow") {
+		t.Fatalf("user = %d %q %q", w.Code, etag, w.Body.String())
+	}
+
+	tests := []struct {
+		path   string
+		header []string
+		code   int
+	}{
+		{"/user", []string{"If-None-Match", etag}, 304},
+		{"/user", []string{"If-None-Match", `W/"x", ` + etag}, 304},
+		{"/user", []string{"If-None-Match", `"x"`}, 200},
+		{"/article", []string{"If-Modified-Since", updated.Format(http.TimeFormat)}, 304},
+		{"/article", []string{"If-Modified-Since", updated.Add(-time.Second).Format(http.TimeFormat)}, 200},
+		{"/stream", []string{"If-None-Match", "*"}, 200},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set(tt.header[0], tt.header[1])
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || (tt.code == 304) != (w.Body.Len() == 0) {
+			t.Errorf("%s %v = %d, %d bytes, want %d", tt.path, tt.header, w.Code, w.Body.Len(), tt.code)
+		}
+	}
+}
@@ -3,13 +3,16 @@ package gow
 import (
 	"fmt"
 	"github.com/gkzy/gow/lib/logy"
-	"github.com/gkzy/gow/render"x
+	"github.com/gkzy/gow/render"
 	"html/template"
+	"log"
 	"net"
 	"net/http"
 	"os"
-	"path"
+	"sort"
+	"strings"
 	"sync"
+	"time"
 )
 
 const (
@@ -46,10 +49,15 @@ func (c HandlersChain) Last() HandlerFunc {
 
 // RouteInfo represents a request route's specification which contains method and path and its handler.
 type RouteInfo struct {
-	Method      string
-	Path        string
-	Handler     string
-	HandlerFunc HandlerFunc
+	Method      string      `json:"method"`
+	Host        string      `json:"host,omitempty"`
+	Listener    string      `json:"listener,omitempty"`
+	Path        string      `json:"path"`
+	Name        string      `json:"name,omitempty"`
+	Handler     string      `json:"handler"`
+	Middleware  []string    `json:"middleware"`
+	Meta        *RouteMeta  `json:"meta,omitempty"`
+	HandlerFunc HandlerFunc `json:"-"`
 }
 
 // RoutesInfo defines a RouteInfo array.
@@ -74,9 +82,15 @@ type Engine struct {
 	gzipOn     bool
 
 	RouterGroup
+	// Deprecated: RedirectTrailingSlash has no effect, the mux matches a path
+	// with or without its trailing slash, so there is nothing to redirect.
 	RedirectTrailingSlash  bool
 	RedirectFixedPath      bool
+	CaseSensitiveRouting   bool
+	PanicOnRouteConflict   bool
 	HandleMethodNotAllowed bool
+	HandleHEAD             bool
+	HandleOPTIONS          bool
 	ForwardedByClientIP    bool
 	AppEngine              bool
 	UseRawPath             bool
@@ -84,13 +98,57 @@ type Engine struct {
 	MaxMultipartMemory     int64
 	RemoveExtraSlash       bool
 
+	// RemoteIPHeaders are the headers carrying the client ip, tried in order when the request comes from a trusted proxy
+	RemoteIPHeaders []string
+
+	// MaxBodyBytes is the max size of request bodies, a larger body is answered with 413 when it is read,
+	// 0 means no limit, use the MaxBodyBytes middleware to change it for some routes
+	MaxBodyBytes int64
+
+	// ReadTimeout, ReadHeaderTimeout, WriteTimeout, IdleTimeout and MaxHeaderBytes are the limits of the http.Server,
+	// see http.Server, WriteTimeout also limits streams like SSE and large downloads
+	ReadTimeout       time.Duration
+	ReadHeaderTimeout time.Duration
+	WriteTimeout      time.Duration
+	IdleTimeout       time.Duration
+	MaxHeaderBytes    int
+	// ErrorLog is the logger of the http.Server errors, nil writes them into logy
+	ErrorLog *log.Logger
+	// ConfigureServer is called with each http.Server before it serves, to set what the engine does not expose
+	ConfigureServer func(srv *http.Server)
+
+	// ShutdownTimeout is the time to drain the in-flight requests after a shutdown signal, 0 waits until they finish
+	ShutdownTimeout time.Duration
+	// ShutdownSignals are the signals shutting down the engine gracefully, default DefaultShutdownSignals,
+	// nil leaves the signals to the app
+	ShutdownSignals []os.Signal
+	// H2C serves HTTP/2 cleartext on the non-TLS listeners too, by prior knowledge and by Upgrade: h2c
+	H2C bool
+	// AltSvc is the Alt-Svc header of the responses below HTTP/3, to advertise an HTTP/3 server of the engine,
+	// like h3=":443"; ma=86400
+	AltSvc string
+	// HotRestart restarts the engine without downtime on SIGHUP and SIGUSR2, see Restart
+	HotRestart bool
+
+	// ValidationLang is the language of validation messages when Accept-Language has neither zh nor en
+	ValidationLang string
+	// ValidationErrorCode is the DataJSON code of ValidationErrors
+	ValidationErrorCode int
+
 	secureJSONPrefix string
 	allNoRoute       HandlersChain
 	allNoMethod      HandlersChain
+	allOptions       HandlersChain
 	noRoute          HandlersChain
 	noMethod         HandlersChain
 	pool             sync.Pool
 	trees            methodTrees
+	hosts            []*hostRouter
+	paramTypes       paramTypes
+	validationRules  *validationRules
+	trustedCIDRs     []*net.IPNet
+	lifecycle        *lifecycle
+	namedRoutes      map[string]*muxNode
 	maxParams        uint16
 }
 
@@ -98,12 +156,24 @@ var _ IRouter = &Engine{}
 
 // New returns a new blank Engine instance without any middleware attached.
 // By default the configuration is:
-// - RedirectTrailingSlash:  true
 // - RedirectFixedPath:      false
+// - CaseSensitiveRouting:   false
+// - PanicOnRouteConflict:   false
 // - HandleMethodNotAllowed: false
+// - HandleHEAD:             false
+// - HandleOPTIONS:          false
 // - ForwardedByClientIP:    true
+// - RemoteIPHeaders:        Forwarded, X-Forwarded-For, X-Real-IP
+// - TrustedProxies:         loopback
+// - ReadHeaderTimeout:      10s
+// - IdleTimeout:            120s
+// - MaxHeaderBytes:         1MB
+// - ShutdownTimeout:        10s
+// - ShutdownSignals:        SIGINT, SIGTERM
 // - UseRawPath:             false
 // - UnescapePathValues:     true
+// - ValidationLang:         zh
+// - ValidationErrorCode:    400
 func New() *Engine {
 	engine := &Engine{
 		RouterGroup: RouterGroup{
@@ -115,16 +185,31 @@ func New() *Engine {
 		RunMode:                defaultMode,
 		AutoRender:             false,
 		FuncMap:                template.FuncMap{},
-		RedirectTrailingSlash:  true,
 		RedirectFixedPath:      false,
+		CaseSensitiveRouting:   false,
+		PanicOnRouteConflict:   false,
 		HandleMethodNotAllowed: false,
+		HandleHEAD:             false,
+		HandleOPTIONS:          false,
 		ForwardedByClientIP:    true,
+		RemoteIPHeaders:        append([]string(nil), defaultRemoteIPHeaders...),
+		ReadHeaderTimeout:      defaultReadHeaderTimeout,
+		IdleTimeout:            defaultIdleTimeout,
+		MaxHeaderBytes:         http.DefaultMaxHeaderBytes,
+		ShutdownTimeout:        defaultShutdownTimeout,
+		ShutdownSignals:        append([]os.Signal(nil), DefaultShutdownSignals...),
 		AppEngine:              defaultAppEngine,
 		UseRawPath:             false,
 		RemoveExtraSlash:       false,
 		UnescapePathValues:     true,
 		MaxMultipartMemory:     defaultMultipartMemory,
 		trees:                  make(methodTrees, 0, 9),
+		paramTypes:             newParamTypes(),
+		validationRules:        newValidationRules(),
+		ValidationLang:         validationLangZH,
+		ValidationErrorCode:    http.StatusBadRequest,
+		namedRoutes:            make(map[string]*muxNode),
+		lifecycle:              newLifecycle(),
 		delims:                 render.Delims{Left: "{{", Right: "}}"},
 		secureJSONPrefix:       "while(1);",
 		viewsPath:              defaultViews,
@@ -133,6 +218,7 @@ func New() *Engine {
 		httpAddr:               defaultHttpAddr,
 	}
 	engine.RouterGroup.engine = engine
+	engine.trustedCIDRs, _ = parseCIDRs(defaultTrustedProxies)
 	engine.pool.New = func() interface{} {
 		return engine.allocateContext()
 	}
@@ -158,6 +244,31 @@ func (engine *Engine) SetAppConfig(app *AppConfig) {
 		engine.httpAddr = app.HTTPAddr
 		engine.sessionOn = app.SessionOn
 		engine.gzipOn = app.GzipOn
+		// 超时、限制与开关，只设置配置文件中存在的值，0 可以关闭超时
+		if app.applies("hot_restart", app.HotRestart) {
+			engine.HotRestart = app.HotRestart
+		}
+		if app.applies("h2c", app.H2C) {
+			engine.H2C = app.H2C
+		}
+		if app.applies("read_timeout", app.ReadTimeout != 0) {
+			engine.ReadTimeout = app.ReadTimeout
+		}
+		if app.applies("read_header_timeout", app.ReadHeaderTimeout != 0) {
+			engine.ReadHeaderTimeout = app.ReadHeaderTimeout
+		}
+		if app.applies("write_timeout", app.WriteTimeout != 0) {
+			engine.WriteTimeout = app.WriteTimeout
+		}
+		if app.applies("idle_timeout", app.IdleTimeout != 0) {
+			engine.IdleTimeout = app.IdleTimeout
+		}
+		if app.applies("max_header_bytes", app.MaxHeaderBytes != 0) {
+			engine.MaxHeaderBytes = app.MaxHeaderBytes
+		}
+		if app.applies("shutdown_timeout", app.ShutdownTimeout != 0) {
+			engine.ShutdownTimeout = app.ShutdownTimeout
+		}
 		// session on
 		if engine.sessionOn {
 			InitSession()
@@ -207,10 +318,7 @@ func (engine *Engine) SetSessionOn(on bool) {
 
 // RouterMap get all router map
 func (engine *Engine) RouterMap() (routes RoutesInfo) {
-	for _, tree := range engine.trees {
-		routes = iterate("", tree.method, routes, tree.root)
-	}
-	return routes
+	return engine.Routes()
 }
 
 // SecureJsonPrefix sets the secureJSONPrefix used in Context.SecureJSON.
@@ -243,6 +351,7 @@ func (engine *Engine) Use(middleware ...HandlerFunc) IRoutes {
 	engine.RouterGroup.Use(middleware...)
 	engine.rebuild404Handlers()
 	engine.rebuild405Handlers()
+	engine.rebuildOptionsHandlers()
 	return engine
 }
 
@@ -254,58 +363,99 @@ func (engine *Engine) rebuild405Handlers() {
 	engine.allNoMethod = engine.combineHandlers(engine.noMethod)
 }
 
-func (engine *Engine) addRoute(method, path string, handlers HandlersChain) {
+func (engine *Engine) rebuildOptionsHandlers() {
+	engine.allOptions = engine.combineHandlers(nil)
+}
+
+// addRoute adds a route to the default trees, or to the trees of host when it is not nil
+func (engine *Engine) addRoute(host *hostRouter, method, path string, handlers HandlersChain) *muxNode {
 	assert1(path[0] == '/', "path must begin with '/'")
 	assert1(method != "", "HTTP method can not be empty")
 	assert1(len(handlers) > 0, "there must be at least one handler")
-	root := engine.trees.get(method)
+	trees := &engine.trees
+	if host != nil {
+		trees = &host.trees
+	}
+	root := trees.get(method)
 	if root == nil {
-		root = new(node)
-		root.fullPath = "/"
-		engine.trees = append(engine.trees, methodTree{method: method, root: root})
+		root = newMuxTree()
+		*trees = append(*trees, methodTree{method: method, root: root})
+	}
+	n := root.addRoute(path, handlers, engine.paramTypes)
+	if host != nil {
+		// the host params come first in c.Params, their types win
+		for key, typ := range host.paramTypes {
+			n.paramTypes[key] = typ
+		}
+	}
+	if exist, winner := root.conflict(n); exist != nil {
+		msg := fmt.Sprintf("route %s %s overlaps %s, %s wins the paths matching both",
+			method, path, exist.fullPath, winner.fullPath)
+		if engine.PanicOnRouteConflict {
+			panic(msg)
+		}
+		debugPrint("[WARNING] " + msg)
 	}
-	root.addRoute(path, handlers)
 
 	// Update maxParams
 	if paramsCount := countParams(path); paramsCount > engine.maxParams {
 		engine.maxParams = paramsCount
 	}
+	return n
 }
 
 // Routes returns a slice of registered routes, including some useful information, such as:
 // the http method, path and the handler name.
 func (engine *Engine) Routes() (routes RoutesInfo) {
+	for _, h := range engine.hosts {
+		for _, tree := range h.trees {
+			routes = engine.iterate(h, tree.method, routes, tree.root)
+		}
+	}
 	for _, tree := range engine.trees {
-		routes = iterate("", tree.method, routes, tree.root)
+		routes = engine.iterate(nil, tree.method, routes, tree.root)
 	}
 	return routes
 }
 
-func iterate(path, method string, routes RoutesInfo, root *node) RoutesInfo {
-	path += root.path
-	if len(root.handlers) > 0 {
-		handlerFunc := root.handlers.Last()
+func (engine *Engine) iterate(host *hostRouter, method string, routes RoutesInfo, root *muxTree) RoutesInfo {
+	var hostPattern, listener string
+	if host != nil {
+		hostPattern, listener = host.pattern, host.listener
+	}
+	for _, n := range root.routes {
+		handlerFunc := n.handlers.Last()
+		middleware := make([]string, 0, len(n.handlers)-1)
+		for _, h := range n.handlers[:len(n.handlers)-1] {
+			middleware = append(middleware, nameOfFunction(h))
+		}
 		routes = append(routes, RouteInfo{
 			Method:      method,
-			Path:        path,
+			Host:        hostPattern,
+			Listener:    listener,
+			Path:        n.fullPath,
+			Name:        n.name,
 			Handler:     nameOfFunction(handlerFunc),
+			Middleware:  middleware,
+			Meta:        n.meta,
 			HandlerFunc: handlerFunc,
 		})
 	}
-	for _, child := range root.children {
-		routes = iterate(path, method, routes, child)
-	}
 	return routes
 }
 
 // Run attaches the router to a http.Server and starts listening and serving HTTP requests.
-// It is a shortcut for http.ListenAndServe(addr, router)
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// The server is shut down gracefully on ShutdownSignals or Shutdown, see OnStart and OnShutdown
+// Note: this method will block the calling goroutine until the engine is shut down or an error happens.
 func (engine *Engine) Run(args ...interface{}) (err error) {
-	defer func() { logy.Error(err) }()
+	defer func() {
+		if err != nil {
+			logy.Error(err)
+		}
+	}()
 
 	if engine.AutoRender {
-		engine.Render = render.HTMLRender{}.NewHTMLRender(engine.viewsPath, engine.FuncMap, engine.delims, engine.AutoRender, engine.RunMode)
+		engine.Render = engine.newHTMLRender()
 	}
 
 	if engine.RunMode == DevMode {
@@ -313,19 +463,27 @@ func (engine *Engine) Run(args ...interface{}) (err error) {
 	}
 
 	address := engine.getAddress(args...)
+	listener, err := engine.listen("tcp", address)
+	if err != nil {
+		return
+	}
 	logy.Infof("[%s] [%s] Listening and serving HTTP on http://%s\n", engine.AppName, engine.RunMode, address)
-	err = http.ListenAndServe(address, engine)
+	err = engine.serve(listener, engine.serveHTTP)
 	return
 }
 
 // RunTLS attaches the router to a http.Server and starts listening and serving HTTPS (secure) requests.
-// It is a shortcut for http.ListenAndServeTLS(addr, certFile, keyFile, router)
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// The server is shut down gracefully like Run
+// Note: this method will block the calling goroutine until the engine is shut down or an error happens.
 func (engine *Engine) RunTLS(certFile, keyFile string, args ...interface{}) (err error) {
-	defer func() { logy.Error(err) }()
+	defer func() {
+		if err != nil {
+			logy.Error(err)
+		}
+	}()
 
 	if engine.AutoRender {
-		engine.Render = render.HTMLRender{}.NewHTMLRender(engine.viewsPath, engine.FuncMap, engine.delims, engine.AutoRender, engine.RunMode)
+		engine.Render = engine.newHTMLRender()
 	}
 
 	if engine.RunMode == DevMode {
@@ -333,32 +491,43 @@ func (engine *Engine) RunTLS(certFile, keyFile string, args ...interface{}) (err
 	}
 
 	address := engine.getAddress(args...)
+	listener, err := engine.listen("tcp", address)
+	if err != nil {
+		return
+	}
 	logy.Infof("[%s] [%s] Listening and serving HTTPS on https://%s\n", engine.AppName, engine.RunMode, address)
-	err = http.ListenAndServeTLS(address, certFile, keyFile, engine)
+	err = engine.serve(listener, func(srv *http.Server, listener net.Listener) error {
+		return srv.ServeTLS(listener, certFile, keyFile)
+	})
 	return
 }
 
 // RunUnix attaches the router to a http.Server and starts listening and serving HTTP requests
 // through the specified unix socket (ie. a file).
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// Note: this method will block the calling goroutine until the engine is shut down or an error happens.
 func (engine *Engine) RunUnix(file string) (err error) {
 	debugPrint("Listening and serving HTTP on unix:/%s", file)
 	defer func() { debugPrintError(err) }()
 
-	listener, err := net.Listen("unix", file)
+	listener, err := engine.listen("unix", file)
 	if err != nil {
 		return
 	}
 	defer listener.Close()
-	defer os.Remove(file)
+	defer func() {
+		// the new process of a hot restart serves the socket file
+		if !engine.restarted() {
+			os.Remove(file)
+		}
+	}()
 
-	err = http.Serve(listener, engine)
+	err = engine.serve(listener, engine.serveHTTP)
 	return
 }
 
 // RunFd attaches the router to a http.Server and starts listening and serving HTTP requests
 // through the specified file descriptor.
-// Note: this method will block the calling goroutine indefinitely unless an error happens.
+// Note: this method will block the calling goroutine until the engine is shut down or an error happens.
 func (engine *Engine) RunFd(fd int) (err error) {
 	debugPrint("Listening and serving HTTP on fd@%d", fd)
 	defer func() { debugPrintError(err) }()
@@ -374,11 +543,11 @@ func (engine *Engine) RunFd(fd int) (err error) {
 }
 
 // RunListener attaches the router to a http.Server and starts listening and serving HTTP requests
-// through the specified net.Listener
+// through the specified net.Listener, until the engine is shut down or an error happens
 func (engine *Engine) RunListener(listener net.Listener) (err error) {
 	debugPrint("Listening and serving HTTP on listener what's bind with address@%s", listener.Addr())
 	defer func() { debugPrintError(err) }()
-	err = http.Serve(listener, engine)
+	err = engine.serve(listener, engine.serveHTTP)
 	return
 }
 
@@ -389,6 +558,9 @@ func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
 	c.Request = req
 	c.reset()
 
+	if engine.AltSvc != "" && req.ProtoMajor < 3 {
+		w.Header().Set("Alt-Svc", engine.AltSvc)
+	}
 	engine.handleHTTPRequest(c)
 
 	engine.pool.Put(c)
@@ -418,6 +590,11 @@ func (engine *Engine) handleHTTPRequest(c *Context) {
 		rPath = cleanPath(rPath)
 	}
 
+	// the routes of host groups are tried first
+	if len(engine.hosts) > 0 && engine.handleHostRequest(c, httpMethod, rPath, unescape) {
+		return
+	}
+
 	// Find root of the tree for the given HTTP method
 	t := engine.trees
 	for i, tl := 0, len(t); i < tl; i++ {
@@ -426,46 +603,108 @@ func (engine *Engine) handleHTTPRequest(c *Context) {
 		}
 		root := t[i].root
 
-		// use like mux router
-		// sam 2020.10.16
-		//value := root.getValue(rPath, c.params, unescape)
-		value := root.getMuxValue(rPath, c.params, unescape)
+		// use the precompiled mux matcher
+		value := root.getValue(rPath, c.params, unescape, engine.CaseSensitiveRouting)
 		if value.params != nil {
 			c.Params = *value.params
 		}
 		if value.handlers != nil {
-			c.handlers = value.handlers
-			c.fullPath = value.fullPath
-			c.Next()
-			c.writermem.WriteHeaderNow()
+			serveRoute(c, value)
 			return
 		}
-		if httpMethod != "CONNECT" && rPath != "/" {
-			if value.tsr && engine.RedirectTrailingSlash {
-				redirectTrailingSlash(c)
-				return
-			}
-			if engine.RedirectFixedPath && redirectFixedPath(c, root, engine.RedirectFixedPath) {
+		break
+	}
+
+	// the host groups and the default routes are tried in the same order as matching
+	if engine.RedirectFixedPath && httpMethod != "CONNECT" && rPath != "/" && engine.redirectFixedRequest(c, httpMethod) {
+		return
+	}
+
+	// serve HEAD by the GET route, the body is discarded
+	if httpMethod == http.MethodHead && engine.HandleHEAD {
+		c.writermem.noBody = true
+		if len(engine.hosts) > 0 && engine.handleHostRequest(c, http.MethodGet, rPath, unescape) {
+			return
+		}
+		if root := engine.trees.get(http.MethodGet); root != nil {
+			if value := root.getValue(rPath, c.params, unescape, engine.CaseSensitiveRouting); value.handlers != nil {
+				serveRoute(c, value)
 				return
 			}
 		}
-		break
+		c.writermem.noBody = false
+	}
+
+	if httpMethod == http.MethodOptions && engine.HandleOPTIONS {
+		if allow := engine.allowed(requestHost(c.Request), c.ListenerName(), rPath, unescape); allow != "" {
+			c.handlers = engine.allOptions
+			c.Header("Allow", allow)
+			c.writermem.status = http.StatusNoContent
+			c.Next()
+			c.writermem.WriteHeaderNow()
+			return
+		}
 	}
 
 	if engine.HandleMethodNotAllowed {
-		for _, tree := range engine.trees {
-			if tree.method == httpMethod {
+		if allow := engine.allowed(requestHost(c.Request), c.ListenerName(), rPath, unescape); allow != "" {
+			c.handlers = engine.allNoMethod
+			c.Header("Allow", allow)
+			serveError(c, http.StatusMethodNotAllowed, default405Body)
+			return
+		}
+	}
+	c.handlers = engine.allNoRoute
+	serveError(c, http.StatusNotFound, default404Body)
+}
+
+// serveRoute runs the handlers of the matched route
+func serveRoute(c *Context, value nodeValue) {
+	c.Params = *value.params
+	c.handlers = value.handlers
+	c.fullPath = value.fullPath
+	c.paramTypes = value.paramTypes
+	c.meta = value.meta
+	if c.engine.MaxBodyBytes > 0 {
+		c.limitBody(c.engine.MaxBodyBytes)
+	}
+	c.Next()
+	c.writermem.WriteHeaderNow()
+}
+
+// allowed returns the methods allowed for path as the Allow header, or "",
+// the routes of the host groups serving host and listener are included
+func (engine *Engine) allowed(host, listener, path string, unescape bool) string {
+	methods := make([]string, 0, len(engine.trees)+2)
+	has := func(method string) bool {
+		for _, m := range methods {
+			if m == method {
+				return true
+			}
+		}
+		return false
+	}
+	for _, trees := range engine.requestTrees(host, listener) {
+		for _, tree := range trees {
+			if has(tree.method) {
 				continue
 			}
-			if value := tree.root.getValue(rPath, nil, unescape); value.handlers != nil {
-				c.handlers = engine.allNoMethod
-				serveError(c, http.StatusMethodNotAllowed, default405Body)
-				return
+			if value := tree.root.getValue(path, nil, unescape, engine.CaseSensitiveRouting); value.handlers != nil {
+				methods = append(methods, tree.method)
 			}
 		}
 	}
-	c.handlers = engine.allNoRoute
-	serveError(c, http.StatusNotFound, default404Body)
+	if len(methods) == 0 {
+		return ""
+	}
+	if engine.HandleHEAD && has(http.MethodGet) && !has(http.MethodHead) {
+		methods = append(methods, http.MethodHead)
+	}
+	if engine.HandleOPTIONS && !has(http.MethodOptions) {
+		methods = append(methods, http.MethodOptions)
+	}
+	sort.Strings(methods)
+	return strings.Join(methods, ", ")
 }
 
 var mimePlain = []string{ContentPlain}
@@ -487,25 +726,24 @@ func serveError(c *Context, code int, defaultMessage []byte) {
 	c.writermem.WriteHeaderNow()
 }
 
-func redirectTrailingSlash(c *Context) {
-	req := c.Request
-	p := req.URL.Path
-	if prefix := path.Clean(c.Request.Header.Get("X-Forwarded-Prefix")); prefix != "." {
-		p = prefix + "/" + req.URL.Path
-	}
-	req.URL.Path = p + "/"
-	if length := len(p); length > 1 && p[length-1] == '/' {
-		req.URL.Path = p[:length-1]
+// redirectFixedRequest redirects c to the fixed path of a route of method,
+// the host groups serving the request are tried before the default routes
+func (engine *Engine) redirectFixedRequest(c *Context, method string) bool {
+	for _, trees := range engine.requestTrees(requestHost(c.Request), c.ListenerName()) {
+		if root := trees.get(method); root != nil && redirectFixedPath(c, root) {
+			return true
+		}
 	}
-	redirectRequest(c)
+	return false
 }
 
-func redirectFixedPath(c *Context, root *node, trailingSlash bool) bool {
+func redirectFixedPath(c *Context, root *muxTree) bool {
 	req := c.Request
 	rPath := req.URL.Path
 
-	if fixedPath, ok := root.findCaseInsensitivePath(cleanPath(rPath), trailingSlash); ok {
-		req.URL.Path = BytesToString(fixedPath)
+	// fix . and .. elements, then the case of the registered route
+	if fixedPath, ok := root.fixPath(cleanPath(rPath)); ok && fixedPath != rPath {
+		req.URL.Path = fixedPath
 		redirectRequest(c)
 		return true
 	}
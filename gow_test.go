@@ -0,0 +1,54 @@
+package gow
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEngineHeadOptions(t *testing.T) {
+	r := New()
+	r.HandleHEAD = true
+	r.HandleOPTIONS = true
+	r.HandleMethodNotAllowed = true
+	r.GET("/user/{uid:int}", func(c *Context) {
+		c.Header("X-User", c.Param("uid"))
+		c.String("sam")
+	})
+	r.PUT("/user/{uid:int}", func(c *Context) {})
+
+	tests := []struct {
+		method string
+		path   string
+		code   int
+		allow  string
+		body   string
+	}{
+		{"GET", "/user/1", 200, "", "sam"},
+		{"HEAD", "/user/1", 200, "", ""},
+		{"OPTIONS", "/user/1", 204, "GET, HEAD, OPTIONS, PUT", ""},
+		{"DELETE", "/user/1", 405, "GET, HEAD, OPTIONS, PUT", "405 method not allowed"},
+		{"OPTIONS", "/nothing", 404, "", "404 page not found"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.code || w.Header().Get("Allow") != tt.allow || w.Body.String() != tt.body {
+			t.Errorf("%s %s = %d, allow %q, body %q, want %d, %q, %q",
+				tt.method, tt.path, w.Code, w.Header().Get("Allow"), w.Body.String(), tt.code, tt.allow, tt.body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/user/7", nil))
+	if w.Header().Get("X-User") != "7" {
+		t.Errorf("HEAD X-User = %q, want 7", w.Header().Get("X-User"))
+	}
+
+	r = New()
+	r.GET("/user", func(c *Context) {})
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("HEAD", "/user", nil))
+	if w.Code != 404 {
+		t.Errorf("HEAD without HandleHEAD = %d, want 404", w.Code)
+	}
+}
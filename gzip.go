@@ -35,3 +35,9 @@ func (g *gzipWriter) WriteHeader(code int) {
 	g.Header().Del("Content-Length")
 	g.ResponseWriter.WriteHeader(code)
 }
+
+// Flush flush the compressed data to the client, it keeps streaming responses working with gzip
+func (g *gzipWriter) Flush() {
+	g.writer.Flush()
+	g.ResponseWriter.Flush()
+}
@@ -0,0 +1,97 @@
+package gow
+
+import (
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync/atomic"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// serveHTTP serve plain HTTP on the listener, HTTP/2 cleartext is served too when H2C is on
+func (engine *Engine) serveHTTP(srv *http.Server, listener net.Listener) error {
+	if engine.H2C {
+		if err := engine.enableH2C(srv); err != nil {
+			listener.Close()
+			return err
+		}
+	}
+	return srv.Serve(listener)
+}
+
+// enableH2C serve HTTP/2 cleartext by prior knowledge and by Upgrade: h2c,
+// the h2c connections are hijacked from srv, they get GOAWAY on Shutdown and are drained by the engine
+func (engine *Engine) enableH2C(srv *http.Server) error {
+	h2s := &http2.Server{IdleTimeout: srv.IdleTimeout}
+	if err := http2.ConfigureServer(srv, h2s); err != nil {
+		return err
+	}
+	handler := h2c.NewHandler(srv.Handler, h2s)
+	lc := engine.lifecycle
+	srv.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if isH2CRequest(r) {
+			atomic.AddInt32(&lc.h2cConns, 1)
+			defer atomic.AddInt32(&lc.h2cConns, -1)
+		}
+		handler.ServeHTTP(w, r)
+	})
+	return nil
+}
+
+// isH2CRequest report whether the request starts an h2c connection
+func isH2CRequest(r *http.Request) bool {
+	if r.Method == "PRI" && r.URL.Path == "*" && r.ProtoMajor == 2 {
+		return true
+	}
+	for _, v := range r.Header.Values("Upgrade") {
+		if strings.EqualFold(strings.TrimSpace(v), "h2c") {
+			return true
+		}
+	}
+	return false
+}
+
+// preloadTypes the as attribute of the preload links by extension
+var preloadTypes = map[string]string{
+	".css":   "style",
+	".js":    "script",
+	".mjs":   "script",
+	".woff":  "font",
+	".woff2": "font",
+	".ttf":   "font",
+	".png":   "image",
+	".jpg":   "image",
+	".jpeg":  "image",
+	".gif":   "image",
+	".svg":   "image",
+	".webp":  "image",
+}
+
+// Push push the resource to the client by HTTP/2 server push,
+// when push is not supported by the connection or the client, a Link preload header is added instead,
+// so the client fetches it early as well, it returns whether the resource is pushed
+//	c.Push("/static/css/app.css", nil)
+//	c.HTML("index.html")
+func (c *Context) Push(target string, opts *http.PushOptions) bool {
+	if pusher := c.Writer.Pusher(); pusher != nil {
+		err := pusher.Push(target, opts)
+		if err == nil {
+			return true
+		}
+		if err != http.ErrNotSupported {
+			debugPrint("[WARNING] push %s: %v", target, err)
+		}
+	}
+	link := "<" + target + ">; rel=preload"
+	if as, ok := preloadTypes[strings.ToLower(path.Ext(strings.SplitN(target, "?", 2)[0]))]; ok {
+		link += "; as=" + as
+		if as == "font" {
+			link += "; crossorigin"
+		}
+	}
+	c.Writer.Header().Add("Link", link)
+	return false
+}
@@ -0,0 +1,184 @@
+package gow
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/net/http2"
+)
+
+// h2cClient a client speaking HTTP/2 cleartext by prior knowledge
+func h2cClient() *http.Client {
+	transport := &http2.Transport{
+		AllowHTTP: true,
+		DialTLS: func(network, addr string, _ *tls.Config) (net.Conn, error) {
+			return net.Dial(network, addr)
+		},
+	}
+	return &http.Client{Transport: transport, Timeout: 5 * time.Second}
+}
+
+func TestEngineH2C(t *testing.T) {
+	r := New()
+	r.ShutdownSignals = nil
+	r.H2C = true
+	r.GET("/proto", func(c *Context) {
+		c.String(c.Request.Proto)
+	})
+	url, done := runTestEngine(t, r)
+
+	tests := []struct {
+		client *http.Client
+		proto  string
+	}{
+		{h2cClient(), "HTTP/2.0"},
+		{http.DefaultClient, "HTTP/1.1"},
+	}
+	for _, tt := range tests {
+		resp, err := tt.client.Get(url + "/proto")
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.Proto != tt.proto || string(b) != tt.proto {
+			t.Errorf("proto = %s, body = %q, want %s", resp.Proto, b, tt.proto)
+		}
+	}
+
+	// the h2c connection of the client is still open, Shutdown sends GOAWAY and waits for it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("RunListener = %v, want nil", err)
+	}
+}
+
+func TestEngineH2CUpgrade(t *testing.T) {
+	r := New()
+	r.ShutdownSignals = nil
+	r.H2C = true
+	r.GET("/proto", func(c *Context) {
+		c.String(c.Request.Proto)
+	})
+	url, done := runTestEngine(t, r)
+	defer func() {
+		r.Shutdown(context.Background())
+		<-done
+	}()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	conn.Write([]byte("GET /proto HTTP/1.1\r\nHost: gow\r\n" +
+		"Connection: Upgrade, HTTP2-Settings\r\nUpgrade: h2c\r\nHTTP2-Settings: AAMAAABkAAQAAP__\r\n\r\n"))
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Upgrade") != "h2c" {
+		t.Fatalf("upgrade response = %d %v", resp.StatusCode, resp.Header)
+	}
+
+	// the request of the upgrade is answered on stream 1
+	conn.Write([]byte(http2.ClientPreface))
+	framer := http2.NewFramer(conn, br)
+	framer.WriteSettings()
+	for {
+		frame, err := framer.ReadFrame()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if data, ok := frame.(*http2.DataFrame); ok && data.StreamID == 1 {
+			if string(data.Data()) != "HTTP/2.0" {
+				t.Errorf("body = %q, want HTTP/2.0", data.Data())
+			}
+			break
+		}
+	}
+}
+
+// pushRecorder a ResponseWriter supporting server push
+type pushRecorder struct {
+	*httptest.ResponseRecorder
+	pushed []string
+}
+
+func (w *pushRecorder) Push(target string, opts *http.PushOptions) error {
+	w.pushed = append(w.pushed, target)
+	return nil
+}
+
+func TestContextPush(t *testing.T) {
+	r := New()
+	r.GET("/", func(c *Context) {
+		if c.Push("/static/app.css", nil) {
+			c.Header("X-Pushed", "1")
+		}
+		c.Push("/static/font.woff2?v=1", nil)
+		c.String("index")
+	})
+
+	// push is not supported, the preload links are added instead
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	want := []string{"</static/app.css>; rel=preload; as=style", "</static/font.woff2?v=1>; rel=preload; as=font; crossorigin"}
+	if got := w.Header()["Link"]; strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Link = %q, want %q", got, want)
+	}
+	if w.Header().Get("X-Pushed") != "" {
+		t.Error("Push = true, want false")
+	}
+
+	pw := &pushRecorder{ResponseRecorder: httptest.NewRecorder()}
+	r.ServeHTTP(pw, httptest.NewRequest("GET", "/", nil))
+	if strings.Join(pw.pushed, ",") != "/static/app.css,/static/font.woff2?v=1" || pw.Header().Get("Link") != "" {
+		t.Errorf("pushed = %q, Link = %q", pw.pushed, pw.Header()["Link"])
+	}
+	if pw.Header().Get("X-Pushed") != "1" {
+		t.Error("Push = false, want true")
+	}
+}
+
+func TestEngineAltSvc(t *testing.T) {
+	r := New()
+	r.AltSvc = `h3=":443"; ma=86400`
+	r.GET("/", func(c *Context) {
+		c.String(c.Request.Proto)
+	})
+
+	tests := []struct {
+		path   string
+		major  int
+		altSvc string
+	}{
+		{"/", 1, r.AltSvc},
+		{"/", 2, r.AltSvc},
+		{"/nothing", 1, r.AltSvc},
+		{"/", 3, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.ProtoMajor = tt.major
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if got := w.Header().Get("Alt-Svc"); got != tt.altSvc {
+			t.Errorf("%s HTTP/%d: Alt-Svc = %q, want %q", tt.path, tt.major, got, tt.altSvc)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+/*
+host and subdomain based routing
+	tenant := r.Host("{tenant}.example.com")
+	tenant.GET("/", handler) // c.Param("tenant")
+host groups are tried in registration order before the default routes,
+requests they do not match fall back to the default routes,
+the listener groups of Serve are host groups matching any host
+*/
+
+package gow
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// hostRouter holds the routes of a host pattern, or of a listener of Serve when listener is set
+type hostRouter struct {
+	pattern  string
+	listener string
+	labels   []*muxNode
+	trees    methodTrees
+	// paramTypes are the types of the host params, they are added to the routes of the group
+	paramTypes map[string]*paramType
+}
+
+// Host returns a router group whose routes only match the requests to the host pattern.
+// The pattern uses the route param syntax for its labels, without a port.
+//	tenant := r.Host("{tenant}.example.com")
+//	tenant.GET("/user/{uid:int}", handler)
+func (engine *Engine) Host(pattern string, handlers ...HandlerFunc) *RouterGroup {
+	return &RouterGroup{
+		Handlers: engine.combineHandlers(handlers),
+		basePath: "/",
+		engine:   engine,
+		host:     engine.addHost(pattern),
+	}
+}
+
+// addHost returns the host router of pattern, it is created when it does not exist
+func (engine *Engine) addHost(pattern string) *hostRouter {
+	pattern = strings.ToLower(strings.TrimSuffix(pattern, "."))
+	assert1(pattern != "", "host pattern can not be empty")
+	for _, h := range engine.hosts {
+		if h.pattern == pattern && h.listener == "" {
+			return h
+		}
+	}
+
+	h := &hostRouter{pattern: pattern, trees: make(methodTrees, 0, 9), paramTypes: make(map[string]*paramType)}
+	for _, label := range strings.Split(pattern, ".") {
+		assert1(label != "", "empty label in host pattern '"+pattern+"'")
+		n := compileMuxSegment(label, pattern, engine.paramTypes)
+		assert1(n.kind != muxCatchAll, "catch-all is not allowed in host pattern '"+pattern+"'")
+		h.labels = append(h.labels, n)
+		if n.typ != nil {
+			h.paramTypes[n.key] = n.typ
+		}
+		for i, key := range n.keys {
+			h.paramTypes[key] = n.types[i]
+		}
+	}
+	engine.hosts = append(engine.hosts, h)
+
+	if paramsCount := countParams(pattern); paramsCount > engine.maxParams {
+		engine.maxParams = paramsCount
+	}
+	return h
+}
+
+// match reports whether host matches the pattern, the host params are appended to m
+func (h *hostRouter) match(host string, m *muxMatch) bool {
+	labels := strings.Split(host, ".")
+	if len(labels) != len(h.labels) {
+		return false
+	}
+	paramsMark, fixedMark := m.mark()
+	for i, n := range h.labels {
+		label := labels[i]
+		switch n.kind {
+		case muxStatic:
+			if !strings.EqualFold(label, n.segment) {
+				m.rollback(paramsMark, fixedMark)
+				return false
+			}
+		case muxParam:
+			if !n.typ.match(label) {
+				m.rollback(paramsMark, fixedMark)
+				return false
+			}
+			m.addParam(n.key, label)
+		case muxPattern:
+			values := n.matchPattern(label, false)
+			if values == nil {
+				m.rollback(paramsMark, fixedMark)
+				return false
+			}
+			for j, key := range n.keys {
+				m.addParam(key, values[j])
+			}
+		}
+	}
+	return true
+}
+
+// serves reports whether the group serves the requests to host received by the listener,
+// the host params are appended to m
+func (h *hostRouter) serves(host, listener string, m *muxMatch) bool {
+	if h.listener != "" && h.listener != listener {
+		return false
+	}
+	return h.pattern == "" || h.match(host, m)
+}
+
+// String returns the host pattern or the listener of the group
+func (h *hostRouter) String() string {
+	if h.pattern == "" {
+		return "listener " + h.listener
+	}
+	return "host " + h.pattern
+}
+
+// requestHost returns the host of the request without the port and the trailing dot
+func requestHost(r *http.Request) string {
+	host := r.Host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(host, ".")
+}
+
+// handleHostRequest serves c by the route of method of the first host group matching the request.
+// It returns false when no host route matches, c.params is left untouched then.
+func (engine *Engine) handleHostRequest(c *Context, method, rPath string, unescape bool) bool {
+	host, listener := requestHost(c.Request), c.ListenerName()
+	for _, h := range engine.hosts {
+		m := &muxMatch{params: c.params}
+		paramsMark, fixedMark := m.mark()
+		if !h.serves(host, listener, m) {
+			continue
+		}
+		root := h.trees.get(method)
+		if root != nil {
+			value := root.getValue(rPath, c.params, unescape, engine.CaseSensitiveRouting)
+			if value.handlers != nil {
+				serveRoute(c, value)
+				return true
+			}
+		}
+		m.rollback(paramsMark, fixedMark)
+	}
+	return false
+}
+
+// requestTrees returns the method trees of the host groups serving host and listener, followed by the default routes
+func (engine *Engine) requestTrees(host, listener string) []methodTrees {
+	trees := make([]methodTrees, 0, len(engine.hosts)+1)
+	for _, h := range engine.hosts {
+		if h.serves(host, listener, &muxMatch{}) {
+			trees = append(trees, h.trees)
+		}
+	}
+	return append(trees, engine.trees)
+}
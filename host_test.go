@@ -0,0 +1,141 @@
+package gow
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEngineHost(t *testing.T) {
+	r := New()
+	write := func(name string) HandlerFunc {
+		return func(c *Context) {
+			c.String(name + ":" + c.Param("tenant") + ":" + c.Param("uid"))
+		}
+	}
+	r.GET("/", write("default"))
+	r.GET("/health", write("health"))
+	r.Host("api-{version:int}.example.com").GET("/", write("api"))
+	tenant := r.Host("{tenant:slug}.example.com")
+	tenant.GET("/", write("tenant"))
+	tenant.Group("/user").GET("/{uid:int}", write("user"))
+
+	tests := []struct {
+		host string
+		path string
+		body string
+	}{
+		{"acme.example.com", "/", "tenant:acme:"},
+		{"Acme.Example.com:8080", "/", "tenant:Acme:"},
+		{"acme.example.com", "/user/12", "user:acme:12"},
+		{"acme.example.com", "/health", "health::"},
+		{"api-2.example.com", "/", "api::"},
+		{"example.com", "/", "default::"},
+		{"a.b.example.com", "/", "default::"},
+		{"acme.example.org", "/", "default::"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Host = tt.host
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tt.body {
+			t.Errorf("%s%s = %q, want %q", tt.host, tt.path, w.Body.String(), tt.body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/user/12", nil)
+	req.Host = "example.com"
+	r.ServeHTTP(w, req)
+	if w.Code != 404 {
+		t.Errorf("code = %d, want 404", w.Code)
+	}
+
+	var hosts int
+	for _, route := range r.Routes() {
+		if route.Host != "" {
+			hosts++
+		}
+	}
+	if hosts != 3 {
+		t.Errorf("host routes = %d, want 3", hosts)
+	}
+}
+
+func TestEngineHostMethods(t *testing.T) {
+	r := New()
+	r.HandleHEAD, r.HandleOPTIONS, r.HandleMethodNotAllowed = true, true, true
+	r.POST("/user/{uid:int}", func(c *Context) {})
+	tenant := r.Host("{tenant}.example.com")
+	tenant.GET("/user/{uid:int}", func(c *Context) {
+		c.String("user " + c.Param("tenant"))
+	})
+
+	tests := []struct {
+		method string
+		host   string
+		code   int
+		allow  string
+	}{
+		{"HEAD", "acme.example.com", 200, ""},
+		{"OPTIONS", "acme.example.com", 204, "GET, HEAD, OPTIONS, POST"},
+		{"PUT", "acme.example.com", 405, "GET, HEAD, OPTIONS, POST"},
+		{"PUT", "example.com", 405, "OPTIONS, POST"},
+		{"HEAD", "example.com", 405, "OPTIONS, POST"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(tt.method, "/user/1", nil)
+		req.Host = tt.host
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Header().Get("Allow") != tt.allow {
+			t.Errorf("%s %s = %d, Allow %q, want %d, %q", tt.method, tt.host, w.Code, w.Header().Get("Allow"), tt.code, tt.allow)
+		}
+		if tt.method == "HEAD" && tt.code == 200 && w.Body.Len() > 0 {
+			t.Errorf("HEAD %s body = %q", tt.host, w.Body.String())
+		}
+	}
+
+	e := r.ExplainRoute("GET", "http://acme.example.com/user/1")
+	if e.Route != "/user/{uid:int}" || e.Host != "{tenant}.example.com" || e.Params.ByName("tenant") != "acme" || e.Params.ByName("uid") != "1" {
+		t.Errorf("explanation = %s, host %q, params %v", e, e.Host, e.Params)
+	}
+	if e := r.ExplainRoute("GET", "/user/1"); e.Route != "" {
+		t.Errorf("explanation without host = %s", e)
+	}
+}
+
+func TestEngineHostParamTypesAndFixedPath(t *testing.T) {
+	r := New()
+	r.RedirectFixedPath = true
+	var shop interface{}
+	r.Host("{shop:int64}.example.com").GET("/Item/{id:int}", func(c *Context) {
+		shop, _ = c.ParamValue("shop")
+		c.String(c.Param("id"))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/Item/3", nil)
+	req.Host = "12.example.com"
+	r.ServeHTTP(w, req)
+	if w.Body.String() != "3" || shop != int64(12) {
+		t.Errorf("body = %q, shop = %#v", w.Body.String(), shop)
+	}
+
+	// the host routes are fixed like the default routes
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/item/../item/3", nil)
+	req.Host = "12.example.com"
+	r.ServeHTTP(w, req)
+	if w.Code != 301 || w.Header().Get("Location") != "/Item/3" {
+		t.Errorf("code = %d, location = %q", w.Code, w.Header().Get("Location"))
+	}
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest("GET", "/item/3", nil)
+	req.Host = "example.com"
+	r.ServeHTTP(w, req)
+	if w.Code != 404 {
+		t.Errorf("other host code = %d, want 404", w.Code)
+	}
+}
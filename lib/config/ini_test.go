@@ -1,27 +1,26 @@
 package config
 
 import (
-	"fmt"
 	"testing"
 )
 
 func TestINI_GetKey(t *testing.T) {
-
-	s := GetString("app_name")
-
-	fmt.Printf("s type=%T,s=%v \n", s, s)
-
-	_, err := WriteContent("123456")
+	// write to a temp dir, the checked-in conf/app.conf is kept as is
+	ini.SetDirectory(t.TempDir())
+	defer func() {
+		ini.SetDirectory("conf/")
+		Reload()
+	}()
+
+	_, err := WriteContent("app_name = gow-test\n")
 	if err != nil {
-		fmt.Println("err:", err)
+		t.Fatal(err)
+	}
+	if err = Reload(); err != nil {
+		t.Fatal(err)
 	}
 
-
-	Reload()
-
-	s = GetString("app_name")
-
-	fmt.Printf("s type=%T,s=%v \n", s, s)
-
-
+	if s := GetString("app_name"); s != "gow-test" {
+		t.Errorf("app_name = %q, want gow-test", s)
+	}
 }
@@ -79,6 +79,20 @@ func (fw *FileWriter) WriteLog(t time.Time, level int, b []byte) {
 	fw.Unlock()
 }
 
+// Close 将日志同步到磁盘并关闭文件，之后写日志会重新打开文件
+//	可以在 gow 的 OnShutdown 中调用
+func (fw *FileWriter) Close() error {
+	fw.Lock()
+	defer fw.Unlock()
+	if fw.file == nil {
+		return nil
+	}
+	fw.file.Sync()
+	err := fw.file.Close()
+	fw.file = nil
+	return err
+}
+
 func (fw *FileWriter) writeFile(t time.Time) {
 	newDate := t.Format(fw.StorageType.getFileFormat())
 	if fw.date != newDate && fw.file != nil {
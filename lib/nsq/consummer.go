@@ -32,15 +32,19 @@ package nsq
 
 import (
 	"fmt"
+	"sync"
+
 	gnsq "github.com/nsqio/go-nsq"
 )
 
 //MessageHandler MessageHandler
 type MessageHandler struct {
 	msgChan   chan *gnsq.Message
-	stop      bool
+	mu        sync.Mutex
+	done      chan struct{} // closed by Stop
 	nsqServer string
 	Channel   string
+	consumers []*gnsq.Consumer
 }
 
 // NewMessageHandler return new MessageHandler
@@ -51,7 +55,7 @@ func NewMessageHandler(nsqServer string, channel string) (mh *MessageHandler, er
 	}
 	mh = &MessageHandler{
 		msgChan:   make(chan *gnsq.Message, 1024),
-		stop:      false,
+		done:      make(chan struct{}),
 		nsqServer: nsqServer,
 		Channel:   channel,
 	}
@@ -72,29 +76,61 @@ func (m *MessageHandler) Registry(topic string, ch chan []byte) {
 	if err != nil {
 		panic(err)
 	}
+	m.mu.Lock()
+	select {
+	case <-m.done:
+		// Stop has been called
+		m.mu.Unlock()
+		consumer.Stop()
+		return
+	default:
+	}
+	m.consumers = append(m.consumers, consumer)
+	m.mu.Unlock()
 	m.process(ch)
 
 }
 
+// Stop 停止接收消息，并等待所有 consumer 停止，Registry 随后返回
+//	可以在 gow 的 OnShutdown 中调用
+func (m *MessageHandler) Stop() {
+	m.mu.Lock()
+	select {
+	case <-m.done:
+	default:
+		close(m.done)
+	}
+	consumers := m.consumers
+	m.consumers = nil
+	m.mu.Unlock()
+
+	for _, consumer := range consumers {
+		consumer.Stop()
+		<-consumer.StopChan
+	}
+}
+
 //process process
 func (m *MessageHandler) process(ch chan<- []byte) {
-	m.stop = false
 	for {
 		select {
 		case message := <-m.msgChan:
-			ch <- message.Body
-			if m.stop {
-				close(m.msgChan)
+			select {
+			case ch <- message.Body:
+			case <-m.done:
 				return
 			}
+		case <-m.done:
+			return
 		}
 	}
 }
 
 //handlerMessage handlerMessage
 func (m *MessageHandler) handlerMessage(message *gnsq.Message) error {
-	if !m.stop {
-		m.msgChan <- message
+	select {
+	case m.msgChan <- message:
+	case <-m.done:
 	}
 	return nil
 }
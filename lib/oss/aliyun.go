@@ -7,6 +7,7 @@ import (
 	"github.com/gkzy/gow/lib/util"
 	"io"
 	"net/http"
+	"strings"
 	"time"
 )
 
@@ -65,6 +66,19 @@ func (c *AliClient) UploadFile(reader io.Reader, dir, filename string) (url stri
 	return
 }
 
+// Delete 删除 UploadFile 或 Upload 返回的远程地址对应的文件
+func (c *AliClient) Delete(url string) error {
+	client, err := oss.New(c.EndPoint, c.AccessKeyId, c.Secret)
+	if err != nil {
+		return fmt.Errorf("[client]init失败:%v", err)
+	}
+	bucket, err := client.Bucket(c.BucketName)
+	if err != nil {
+		return err
+	}
+	return bucket.DeleteObject(strings.TrimPrefix(url, c.ServerUrl))
+}
+
 // Upload 上传文件	返回远程地址及错误
 //		url,err:=client.Upload(reader,dir,ext)
 //		会强制重命名文件名
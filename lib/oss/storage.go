@@ -0,0 +1,27 @@
+package oss
+
+import "io"
+
+// Storage 将 gow.Context.Upload 的文件上传到 oss，实现了 gow.UploadStorage
+//	storage := oss.NewStorage(client, "avatar")
+//	files, err := c.Upload(gow.UploadConfig{Fields: fields, Storage: storage})
+type Storage struct {
+	Client *AliClient
+	Dir    string
+}
+
+// NewStorage NewStorage
+func NewStorage(client *AliClient, dir string) *Storage {
+	return &Storage{Client: client, Dir: dir}
+}
+
+// Store 上传文件，返回远程地址
+//	like:   /dir/20210309/name
+func (s *Storage) Store(r io.Reader, name string) (string, error) {
+	return s.Client.UploadFile(r, s.Dir, name)
+}
+
+// Remove 删除 Store 上传的文件
+func (s *Storage) Remove(location string) error {
+	return s.Client.Delete(location)
+}
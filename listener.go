@@ -0,0 +1,168 @@
+package gow
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+
+	"github.com/gkzy/gow/lib/logy"
+)
+
+// ServeConfig a listener of Serve
+type ServeConfig struct {
+	// Name the name of the listener, the routes of engine.Listener(Name) are served only by it
+	Name string
+
+	// Network tcp or unix, default tcp
+	Network string
+
+	// Addr the address to listen, like :8080, or the file of a unix socket
+	Addr string
+
+	// CertFile and KeyFile serve HTTPS, TLSConfig can be used instead with its certificates
+	CertFile  string
+	KeyFile   string
+	TLSConfig *tls.Config
+
+	// Listener serve an existing listener, Network and Addr are ignored
+	Listener net.Listener
+
+	// Handler serve the listener by the handler instead of the engine
+	Handler http.Handler
+}
+
+// listenerNameKey the context key of the name of the listener serving the request
+type listenerNameKey struct{}
+
+// Listener returns a router group whose routes only match the requests received by the listener of the name,
+// see Serve
+//	admin := r.Listener("internal")
+//	admin.GET("/metrics", metrics)
+func (engine *Engine) Listener(name string, handlers ...HandlerFunc) *RouterGroup {
+	assert1(name != "", "listener name can not be empty")
+	var h *hostRouter
+	for _, router := range engine.hosts {
+		if router.pattern == "" && router.listener == name {
+			h = router
+		}
+	}
+	if h == nil {
+		h = &hostRouter{listener: name, trees: make(methodTrees, 0, 9)}
+		engine.hosts = append(engine.hosts, h)
+	}
+	return &RouterGroup{
+		Handlers: engine.combineHandlers(handlers),
+		basePath: "/",
+		engine:   engine,
+		host:     h,
+	}
+}
+
+// ListenerName return the name of the ServeConfig of the listener receiving the request
+func (c *Context) ListenerName() string {
+	name, _ := c.Request.Context().Value(listenerNameKey{}).(string)
+	return name
+}
+
+// Serve bind all the listeners and serve them until the engine is shut down or one of them fails,
+// the others are shut down gracefully then and the first error is returned
+//	err := r.Serve(
+//		gow.ServeConfig{Addr: ":443", CertFile: "cert.pem", KeyFile: "key.pem"},
+//		gow.ServeConfig{Name: "internal", Addr: "10.0.0.2:8080"},
+//		gow.ServeConfig{Network: "unix", Addr: "/run/app.sock"},
+//	)
+func (engine *Engine) Serve(configs ...ServeConfig) error {
+	assert1(len(configs) > 0, "there must be at least one listener")
+	if engine.AutoRender {
+		engine.Render = engine.newHTMLRender()
+	}
+	if engine.RunMode == DevMode {
+		fmt.Println(logo)
+	}
+
+	listeners := make([]net.Listener, 0, len(configs))
+	for _, config := range configs {
+		listener, err := engine.listenConfig(config)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			logy.Error(err)
+			return err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	errs := make(chan error, len(configs))
+	for i, config := range configs {
+		go func(config ServeConfig, listener net.Listener) {
+			errs <- engine.serveConfig(config, listener)
+		}(config, listeners[i])
+	}
+	var first error
+	for range configs {
+		err := <-errs
+		if err == nil || first != nil {
+			continue
+		}
+		first = err
+		logy.Error(err)
+		engine.shutdownWithTimeout()
+	}
+	for _, config := range configs {
+		if config.Listener == nil && config.Network == "unix" && !engine.restarted() {
+			os.Remove(config.Addr)
+		}
+	}
+	return first
+}
+
+// listenConfig return the listener of the config
+func (engine *Engine) listenConfig(config ServeConfig) (net.Listener, error) {
+	if config.Listener != nil {
+		return config.Listener, nil
+	}
+	network := config.Network
+	if network == "" {
+		network = "tcp"
+	}
+	if network != "tcp" && network != "unix" {
+		return nil, errors.New("gow: unsupported network " + network)
+	}
+	return engine.listen(network, config.Addr)
+}
+
+// serveConfig serve the listener of the config by a managed server
+func (engine *Engine) serveConfig(config ServeConfig, listener net.Listener) error {
+	isTLS := config.CertFile != "" || config.TLSConfig != nil
+	scheme := "http"
+	if isTLS {
+		scheme = "https"
+	}
+	logy.Infof("[%s] [%s] Listening and serving %s on %s://%s %s\n", engine.AppName, engine.RunMode, scheme, listener.Addr().Network(), listener.Addr(), config.Name)
+
+	return engine.serve(listener, func(srv *http.Server, listener net.Listener) error {
+		if config.Handler != nil {
+			srv.Handler = config.Handler
+		}
+		base := srv.BaseContext
+		srv.BaseContext = func(l net.Listener) context.Context {
+			ctx := context.Background()
+			if base != nil {
+				ctx = base(l)
+			}
+			return context.WithValue(ctx, listenerNameKey{}, config.Name)
+		}
+		if !isTLS {
+			return engine.serveHTTP(srv, listener)
+		}
+		if config.TLSConfig != nil {
+			srv.TLSConfig = config.TLSConfig.Clone()
+		}
+		return srv.ServeTLS(listener, config.CertFile, config.KeyFile)
+	})
+}
@@ -0,0 +1,155 @@
+package gow
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func testListen(t *testing.T) net.Listener {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return listener
+}
+
+func testGet(client *http.Client, url string) (int, string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	b, _ := ioutil.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestEngineServe(t *testing.T) {
+	r := New()
+	r.ShutdownSignals = nil
+	r.GET("/", func(c *Context) {
+		c.String("public " + c.ListenerName())
+	})
+	admin := r.Listener("internal")
+	admin.GET("/metrics", func(c *Context) {
+		c.String("metrics")
+	})
+
+	public, internal := testListen(t), testListen(t)
+	sock := filepath.Join(t.TempDir(), "gow.sock")
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Serve(
+			ServeConfig{Listener: public},
+			ServeConfig{Name: "internal", Listener: internal},
+			ServeConfig{Name: "internal", Network: "unix", Addr: sock},
+		)
+	}()
+
+	publicURL, internalURL := "http://"+public.Addr().String(), "http://"+internal.Addr().String()
+	unixClient := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}}
+	for i := 0; i < 100; i++ {
+		if code, _ := testGet(unixClient, "http://unix/"); code == http.StatusOK {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	tests := []struct {
+		client *http.Client
+		url    string
+		code   int
+		body   string
+	}{
+		{http.DefaultClient, publicURL + "/", 200, "public "},
+		{http.DefaultClient, publicURL + "/metrics", 404, "404 page not found"},
+		{http.DefaultClient, internalURL + "/", 200, "public internal"},
+		{http.DefaultClient, internalURL + "/metrics", 200, "metrics"},
+		{unixClient, "http://unix/metrics", 200, "metrics"},
+	}
+	for _, tt := range tests {
+		if code, body := testGet(tt.client, tt.url); code != tt.code || body != tt.body {
+			t.Errorf("GET %s = %d %q, want %d %q", tt.url, code, body, tt.code, tt.body)
+		}
+	}
+
+	var listener string
+	for _, route := range r.Routes() {
+		if route.Path == "/metrics" {
+			listener = route.Listener
+		}
+	}
+	if listener != "internal" {
+		t.Errorf("route listener = %q, want internal", listener)
+	}
+
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("Serve = %v, want nil", err)
+	}
+	if code, _ := testGet(http.DefaultClient, publicURL+"/"); code != 0 {
+		t.Errorf("the public listener is still served")
+	}
+}
+
+func TestEngineServeFirstError(t *testing.T) {
+	r := New()
+	r.ShutdownSignals = nil
+	r.GET("/", func(c *Context) {
+		c.String("ok")
+	})
+	var shutdown bool
+	r.OnShutdown(func(ctx context.Context) error {
+		shutdown = true
+		return nil
+	})
+
+	public := testListen(t)
+	done := make(chan error, 1)
+	go func() {
+		done <- r.Serve(
+			ServeConfig{Listener: public},
+			ServeConfig{Listener: testListen(t), CertFile: "missing.pem", KeyFile: "missing.key"},
+		)
+	}()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Serve = nil, want the TLS error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve does not return when a listener fails")
+	}
+	if !shutdown {
+		t.Error("the engine is not shut down")
+	}
+	if code, _ := testGet(http.DefaultClient, "http://"+public.Addr().String()+"/"); code != 0 {
+		t.Errorf("the public listener is still served")
+	}
+
+	// a bind error closes the bound listeners
+	r = New()
+	used := testListen(t)
+	defer used.Close()
+	unused := testListen(t)
+	addr := unused.Addr().String()
+	unused.Close()
+	if err := r.Serve(ServeConfig{Addr: addr}, ServeConfig{Addr: used.Addr().String()}); err == nil {
+		t.Fatal("Serve = nil, want the bind error")
+	}
+	if l, err := net.Listen("tcp", addr); err != nil {
+		t.Errorf("the bound listener is not closed: %v", err)
+	} else {
+		l.Close()
+	}
+}
@@ -0,0 +1,123 @@
+/*
+mount an http.Handler or another *gow.Engine under a prefix
+	admin := gow.New()
+	admin.GET("/user", handler)
+	r.Mount("/admin", admin)          // GET /admin/user, with the middleware of r
+	r.MountIsolated("/admin", admin)  // without the middleware of r
+the prefix is stripped from the request path and appended to X-Forwarded-Prefix,
+the X-Forwarded-Prefix of the request is kept only when it comes from a trusted proxy,
+it can have params, like /tenant/{tid}/admin
+*/
+
+package gow
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// mountParam is the catch-all key of mounted routes
+const mountParam = "mountpath"
+
+// mountPrefixKey the context key of the X-Forwarded-Prefix set by Mount
+type mountPrefixKey struct{}
+
+// Mount serves all the requests under relativePath by handler, with the group middleware.
+//	r.Mount("/admin", adminEngine)
+//	r.Mount("/debug/pprof", http.DefaultServeMux)
+func (group *RouterGroup) Mount(relativePath string, handler http.Handler) IRoutes {
+	return group.mount(relativePath, handler, false)
+}
+
+// MountIsolated is like Mount, but the group middleware is not run
+func (group *RouterGroup) MountIsolated(relativePath string, handler http.Handler) IRoutes {
+	return group.mount(relativePath, handler, true)
+}
+
+func (group *RouterGroup) mount(relativePath string, handler http.Handler, isolated bool) IRoutes {
+	assert1(handler != nil, "mounted handler can not be nil")
+	assert1(!strings.Contains(relativePath, "*"), "mount path can not contain a catch-all")
+	prefix := strings.TrimSuffix(group.calculateAbsolutePath(relativePath), "/")
+	h := mountHandler(handler)
+
+	root := prefix
+	if root == "" {
+		root = "/"
+	}
+	paths := []string{root, prefix + "/*" + mountParam}
+	routes := make([]*muxNode, 0, len(anyMethods)*len(paths))
+	for _, p := range paths {
+		for _, method := range anyMethods {
+			handlers := HandlersChain{h}
+			if !isolated {
+				handlers = group.combineHandlers(handlers)
+			}
+			routes = append(routes, group.engine.addRoute(group.host, method, p, handlers))
+		}
+	}
+	group.lastRoutes = routes
+	return group.returnObj()
+}
+
+// mountHandler serves the request by handler with the mount prefix stripped
+func mountHandler(handler http.Handler) HandlerFunc {
+	return func(c *Context) {
+		rest := c.Param(mountParam)
+		if c.engine.UseRawPath && !c.engine.UnescapePathValues {
+			if u, err := url.PathUnescape(rest); err == nil {
+				rest = u
+			}
+		}
+		if rest == "" {
+			rest = "/"
+		}
+		prefix := strings.TrimSuffix(strings.TrimSuffix(c.Request.URL.Path, "/"), strings.TrimSuffix(rest, "/"))
+
+		forwarded := strings.TrimSuffix(forwardedPrefix(c), "/") + prefix
+		req := c.Request.Clone(context.WithValue(c.Request.Context(), mountPrefixKey{}, forwarded))
+		req.URL.Path = rest
+		req.URL.RawPath = stripRawPrefix(c.Request.URL.RawPath, prefix, rest)
+		req.RequestURI = req.URL.RequestURI()
+		req.Header.Set("X-Forwarded-Prefix", forwarded)
+		handler.ServeHTTP(c.Writer, req)
+	}
+}
+
+// forwardedPrefix return the path prefix of the request in front of the proxies and mount points,
+// it is the prefix set by Mount, or the X-Forwarded-Prefix sent by a trusted proxy
+func forwardedPrefix(c *Context) string {
+	if prefix, ok := c.Request.Context().Value(mountPrefixKey{}).(string); ok {
+		return prefix
+	}
+	if c.fromTrustedProxy() {
+		return c.GetHeader("X-Forwarded-Prefix")
+	}
+	return ""
+}
+
+// stripRawPrefix returns the escaped form of rest, rawPath with the escaped form of prefix stripped,
+// like http.StripPrefix it returns "" when the result is not an encoding of rest
+func stripRawPrefix(rawPath, prefix, rest string) string {
+	if rawPath == "" {
+		return ""
+	}
+	for i := 0; i <= len(rawPath); i++ {
+		if i < len(rawPath) && rawPath[i] != '/' {
+			continue
+		}
+		if p, err := url.PathUnescape(rawPath[:i]); err != nil || p != prefix {
+			continue
+		}
+		rawRest := rawPath[i:]
+		if rawRest == "" {
+			rawRest = "/"
+		}
+		if r, err := url.PathUnescape(rawRest); err == nil && r == rest {
+			return rawRest
+		}
+		break
+	}
+	return ""
+}
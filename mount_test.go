@@ -0,0 +1,87 @@
+package gow
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouterGroupMount(t *testing.T) {
+	admin := New()
+	admin.GET("/", func(c *Context) {
+		c.String("admin home")
+	})
+	admin.GET("/user/{uid:int}", func(c *Context) {
+		c.String("user " + c.Param("uid") + " " + c.GetHeader("X-Forwarded-Prefix") + " " + c.GetHeader("X-Outer"))
+	})
+
+	r := New()
+	r.Use(func(c *Context) {
+		c.Request.Header.Set("X-Outer", "on")
+		c.Next()
+	})
+	r.Mount("/admin", admin)
+	r.Group("/tenant/{tid}").MountIsolated("/admin", admin)
+	r.Mount("/std", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("std " + req.URL.Path))
+	}))
+
+	tests := []struct {
+		path   string
+		prefix string
+		remote string
+		body   string
+	}{
+		{"/admin", "", "", "admin home"},
+		{"/admin/", "", "", "admin home"},
+		{"/admin/user/1", "", "", "user 1 /admin on"},
+		{"/admin/user/1", "/api", "127.0.0.1:1234", "user 1 /api/admin on"},
+		// the prefix of an untrusted client is dropped
+		{"/admin/user/1", "//evil.com", "203.0.113.7:1234", "user 1 /admin on"},
+		{"/tenant/acme/admin/user/2", "", "", "user 2 /tenant/acme/admin "},
+		{"/std/a/b/", "", "", "std /a/b/"},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", tt.path, nil)
+		if tt.prefix != "" {
+			req.Header.Set("X-Forwarded-Prefix", tt.prefix)
+		}
+		if tt.remote != "" {
+			req.RemoteAddr = tt.remote
+		}
+		r.ServeHTTP(w, req)
+		if w.Body.String() != tt.body {
+			t.Errorf("%s = %q, want %q", tt.path, w.Body.String(), tt.body)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/admin/nothing", nil))
+	if w.Code != 404 {
+		t.Errorf("/admin/nothing = %d, want 404", w.Code)
+	}
+}
+
+func TestRouterGroupMountEscapedPath(t *testing.T) {
+	files := New()
+	files.UseRawPath = true
+	files.GET("/files/{dir}/{name}", func(c *Context) {
+		c.String("file " + c.Param("name"))
+	})
+	files.GET("/files/*path", func(c *Context) {
+		c.String("path " + c.Param("path") + " " + c.Request.URL.EscapedPath())
+	})
+	r := New()
+	r.Mount("/tenant/{tid}/disk", files)
+
+	// the escaped slash below the mount point is kept for the routing of the mounted engine
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/tenant/acme/disk/files/a%2Fb", nil))
+	if body := w.Body.String(); body != "path /a/b /files/a%2Fb" {
+		t.Errorf("body = %q", body)
+	}
+	if got := stripRawPrefix("/t/a%2Fb/x", "/t/a/b", "/x"); got != "/x" {
+		t.Errorf("stripRawPrefix = %q, want /x", got)
+	}
+}
@@ -1,161 +1,569 @@
 /*
 like mux router
-use regexp
+routes are compiled into a segment tree when they are registered,
+so matching a request never builds or runs a whole-path regexp
 sam
 */
 
 package gow
 
 import (
+	"fmt"
 	"net/url"
 	"regexp"
 	"strings"
 )
 
-type routerPathInfo struct {
-	Path     string
-	fullPath string
+// muxKind is the kind of a compiled path segment
+type muxKind uint8
+
+const (
+	muxStatic   muxKind = iota // /user
+	muxPattern                 // /read_{id:int}.html
+	muxParam                   // /{uid} or /{uid:int}
+	muxCatchAll                // /*filepath
+)
+
+func (k muxKind) String() string {
+	switch k {
+	case muxStatic:
+		return "static"
+	case muxPattern:
+		return "pattern"
+	case muxParam:
+		return "param"
+	default:
+		return "catch-all"
+	}
+}
+
+// muxTree is the precompiled matcher of one method tree.
+// It is built by addRoute and is read only while serving requests.
+type muxTree struct {
+	root *muxNode
+	// routes are the leaf nodes in registration order
+	routes []*muxNode
+}
+
+// muxNode is a single compiled path segment
+type muxNode struct {
+	kind    muxKind
+	segment string
+
+	// key and typ are used by muxParam and muxCatchAll
+	key string
+	typ *paramType
+
+	// regexp, keys, groups and types are used by muxPattern,
+	// foldRegexp ignores the case of the literals
+	regexp     *regexp.Regexp
+	foldRegexp *regexp.Regexp
+	keys       []string
+	groups     []int
+	types      []*paramType
+
+	// statics are keyed by segment, foldStatics by lower case segment
+	statics     map[string]*muxNode
+	foldStatics map[string]*muxNode
+	wildcards   []*muxNode
+
 	handlers HandlersChain
-	params   *Params
-	tsr      bool
-	nType    nodeType
-}
-
-// RouterPath defines a routerPathInfo array.
-type RouterPath []routerPathInfo
-
-// getMuxValue Returns the handle registered with the given path (key). The values of
-// wildcards are saved to a map.
-// If no handle can be found, a TSR (trailing slash redirect) recommendation is
-// made if a handle exists with an extra (without the) trailing slash for the
-// given path.
-func (n *node) getMuxValue(path string, params *Params, unescape bool) (value nodeValue) {
-	rpm := getNodeRouterPathMap(n)
-	routerPath, ok := getMatchPath(path, rpm, unescape)
-	if ok {
-		if params != nil {
-			value.params = params
-			value.params = routerPath.params
+	fullPath string
+	// path is the nodes from the root to the route
+	path       []*muxNode
+	paramTypes map[string]*paramType
+	meta       *RouteMeta
+	name       string
+}
+
+// muxMatch holds the state of matching a single request path
+type muxMatch struct {
+	params        *Params
+	unescape      bool
+	caseSensitive bool
+
+	// fixed collects the registered spelling of every segment when fix is set
+	fix   bool
+	fixed []string
+
+	// trace collects the matching steps for ExplainRoute
+	trace *[]string
+}
+
+func newMuxTree() *muxTree {
+	return &muxTree{root: new(muxNode)}
+}
+
+// addRoute compiles path and adds it to the tree, it returns the leaf node.
+// Not concurrency-safe!
+func (t *muxTree) addRoute(path string, handlers HandlersChain, types paramTypes) *muxNode {
+	n := t.root
+	keyTypes := make(map[string]*paramType)
+	segments := splitMuxPath(path)
+	nodes := make([]*muxNode, 0, len(segments))
+	for i, seg := range segments {
+		child := compileMuxSegment(seg, path, types)
+		if child.kind == muxCatchAll && i != len(segments)-1 {
+			panic("catch-all routes are only allowed at the end of the path in path '" + path + "'")
+		}
+		n = n.addChild(child)
+		nodes = append(nodes, n)
+		if n.typ != nil {
+			keyTypes[n.key] = n.typ
+		}
+		for j, key := range n.keys {
+			keyTypes[key] = n.types[j]
+		}
+	}
+	if n.handlers != nil {
+		panic("handlers are already registered for path '" + path + "'")
+	}
+	n.handlers = handlers
+	n.fullPath = path
+	n.path = nodes
+	n.paramTypes = keyTypes
+	t.routes = append(t.routes, n)
+	return n
+}
+
+// conflict returns the first route overlapping n, see overlap, and the route winning the paths matching both
+func (t *muxTree) conflict(n *muxNode) (exist, winner *muxNode) {
+	for _, r := range t.routes {
+		if winner = t.overlap(r, n); winner != nil {
+			return r, winner
+		}
+	}
+	return nil, nil
+}
+
+// overlap reports whether some paths match both routes a and b, which are ambiguous then,
+// it returns the route winning those paths or nil.
+// Segments differing by static and wildcard, or by wildcard priority, are not ambiguous,
+// the order of matching decides them.
+func (t *muxTree) overlap(a, b *muxNode) *muxNode {
+	if a == b || len(a.path) != len(b.path) {
+		return nil
+	}
+	var winner *muxNode
+	for i, x := range a.path {
+		y := b.path[i]
+		if x == y {
+			continue
+		}
+		if !x.overlaps(y) {
+			return nil
+		}
+		if winner != nil {
+			continue
 		}
-		value.handlers = routerPath.handlers
-		value.fullPath = routerPath.fullPath
+		// the wildcard added to the parent first is tried first
+		parent := t.root
+		if i > 0 {
+			parent = a.path[i-1]
+		}
+		for _, w := range parent.wildcards {
+			if w == x {
+				winner = a
+				break
+			}
+			if w == y {
+				winner = b
+				break
+			}
+		}
+	}
+	return winner
+}
+
+// overlaps reports whether some segments match both statics or both wildcards of the same priority
+func (n *muxNode) overlaps(other *muxNode) bool {
+	if n.kind != other.kind {
+		return false
+	}
+	if n.kind == muxStatic {
+		return strings.EqualFold(n.segment, other.segment)
+	}
+	if n.priority() != other.priority() {
+		return false
+	}
+	switch n.kind {
+	case muxParam:
+		return !n.typ.disjoint(other.typ)
+	case muxPattern:
+		if muxShape(n.segment) != muxShape(other.segment) {
+			return false
+		}
+		for i, typ := range n.types {
+			if typ.disjoint(other.types[i]) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// muxShape removes the params of a segment but their braces,
+// so read_{id:int}.html and read_{name}.html have the same shape read_{}.html
+func muxShape(seg string) string {
+	if seg[0] == '*' {
+		return "*"
+	}
+	var buf strings.Builder
+	rest := seg
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			buf.WriteString(strings.ToLower(rest))
+			break
+		}
+		end := closingBrace(rest[start:])
+		if end < 0 {
+			buf.WriteString(strings.ToLower(rest))
+			break
+		}
+		end += start
+		buf.WriteString(strings.ToLower(rest[:start]) + "{}")
+		rest = rest[end+1:]
+	}
+	return buf.String()
+}
+
+// getValue returns the handle registered with the given path.
+// The values of wildcards are appended to params when params is not nil,
+// they always keep the case of the request path.
+func (t *muxTree) getValue(path string, params *Params, unescape, caseSensitive bool) (value nodeValue) {
+	m := &muxMatch{params: params, unescape: unescape, caseSensitive: caseSensitive}
+	n := t.root.find(path, m)
+	if n == nil {
 		return
 	}
+	value.params = params
+	value.handlers = n.handlers
+	value.fullPath = n.fullPath
+	value.paramTypes = n.paramTypes
+	value.meta = n.meta
 	return
 }
 
-// getMatchPath return routerPathInfo
-// regexp match
-func getMatchPath(path string, rp RouterPath, unescape bool) (*routerPathInfo, bool) {
-	lastChar := path[len(path)-1:]
-	if path != "/" && lastChar == "/" && !strings.Contains(path, ".") {
-		path = path[:len(path)-1]
-	}
-	path = strings.ReplaceAll(path, "//", "/")
-	//request path ignore  case
-	path = strings.ToLower(path)
-	for _, p := range rp {
-		regPath, keys := mathPath(p.Path)
-		if path == regPath {
-			return &p, true
-		} else {
-			// all reg match
-			ok, _ := regexp.MatchString("^"+regPath+"$", path)
-			if ok {
-				valueRegexp := regexp.MustCompile(regPath)
-				if unescape {
-					if v, err := url.QueryUnescape(path); err == nil {
-						path = v
-					}
+// fixPath makes a case-insensitive lookup of path and returns it
+// spelled like the registered route, like /USER/Sam to /user/Sam
+func (t *muxTree) fixPath(path string) (string, bool) {
+	m := &muxMatch{fix: true}
+	if t.root.find(path, m) == nil {
+		return "", false
+	}
+	return "/" + strings.Join(m.fixed, "/"), true
+}
+
+// addChild returns the existing child compiled from the same segment,
+// or inserts child keeping wildcards ordered by priority.
+func (n *muxNode) addChild(child *muxNode) *muxNode {
+	if child.kind == muxStatic {
+		if n.statics == nil {
+			n.statics = make(map[string]*muxNode)
+		}
+		if exist, ok := n.statics[child.segment]; ok {
+			return exist
+		}
+		n.statics[child.segment] = child
+		if n.foldStatics == nil {
+			n.foldStatics = make(map[string]*muxNode)
+		}
+		if fold := strings.ToLower(child.segment); n.foldStatics[fold] == nil {
+			n.foldStatics[fold] = child
+		}
+		return child
+	}
+
+	for _, exist := range n.wildcards {
+		if exist.segment == child.segment {
+			return exist
+		}
+	}
+	i := len(n.wildcards)
+	for i > 0 && n.wildcards[i-1].priority() > child.priority() {
+		i--
+	}
+	n.wildcards = append(n.wildcards, nil)
+	copy(n.wildcards[i+1:], n.wildcards[i:])
+	n.wildcards[i] = child
+	return child
+}
+
+// priority orders wildcards, lower is tried first.
+// mixed segments are the most specific, catch-all the least.
+func (n *muxNode) priority() int {
+	switch n.kind {
+	case muxPattern:
+		return 0
+	case muxParam:
+		// typed params, like {uid:int}, are tried before {uid}
+		if strings.IndexByte(n.segment, ':') >= 0 {
+			return 1
+		}
+		return 2
+	default:
+		return 3
+	}
+}
+
+// find walks the tree with backtracking. static segments win over wildcards.
+func (n *muxNode) find(path string, m *muxMatch) *muxNode {
+	for len(path) > 0 && path[0] == '/' {
+		path = path[1:]
+	}
+	if path == "" {
+		if n.handlers != nil {
+			return n
+		}
+		if m.trace != nil {
+			m.tracef("the path ends at '%s', no route is registered there", n.segment)
+		}
+		return nil
+	}
+
+	seg, rest := path, ""
+	if i := strings.IndexByte(path, '/'); i >= 0 {
+		seg, rest = path[:i], path[i:]
+	}
+
+	child, ok := n.statics[seg]
+	if ok {
+		if m.trace != nil {
+			m.tracef("'%s' matches static '%s'", seg, child.segment)
+		}
+		if found := child.findStatic(rest, m); found != nil {
+			return found
+		}
+	}
+	if !m.caseSensitive {
+		if fold, ok := n.foldStatics[strings.ToLower(seg)]; ok && fold != child {
+			if m.trace != nil {
+				m.tracef("'%s' matches static '%s' ignoring case", seg, fold.segment)
+			}
+			if found := fold.findStatic(rest, m); found != nil {
+				return found
+			}
+		}
+	}
+
+	for _, child := range n.wildcards {
+		paramsMark, fixedMark := m.mark()
+		switch child.kind {
+		case muxCatchAll:
+			if m.trace != nil {
+				m.tracef("'/%s' is taken by catch-all '%s'", path, child.segment)
+			}
+			m.addParam(child.key, "/"+path)
+			m.addFixed(path)
+			return child
+		case muxParam:
+			if !child.typ.match(seg) {
+				if m.trace != nil {
+					m.tracef("'%s' does not match param '%s'", seg, child.segment)
 				}
-				values := valueRegexp.FindStringSubmatch(path)
-				params := new(Params)
-				for i, k := range keys {
-					*params = append(*params, Param{
-						Key:   strings.ReplaceAll(strings.ReplaceAll(k, "{", ""), "}", ""),
-						Value: values[i+1],
-					})
+				continue
+			}
+			m.addParam(child.key, seg)
+		case muxPattern:
+			values := child.matchPattern(seg, m.caseSensitive)
+			if values == nil {
+				if m.trace != nil {
+					m.tracef("'%s' does not match pattern '%s'", seg, child.segment)
 				}
-				p.params = params
-				return &p, ok
+				continue
 			}
+			for i, key := range child.keys {
+				m.addParam(key, values[i])
+			}
+		}
+		if m.trace != nil {
+			m.tracef("'%s' matches %s '%s'", seg, child.kind, child.segment)
+		}
+		m.addFixed(seg)
+		if found := child.find(rest, m); found != nil {
+			return found
+		}
+		if m.trace != nil {
+			m.tracef("backtracking from '%s'", child.segment)
 		}
+		m.rollback(paramsMark, fixedMark)
 	}
-	return nil, false
+	if m.trace != nil {
+		if n.segment == "" {
+			m.tracef("'%s' matches no segment at the root", seg)
+		} else {
+			m.tracef("'%s' matches no segment after '%s'", seg, n.segment)
+		}
+	}
+	return nil
 }
 
-var (
-	intRegexp  = []byte(`(\d+)`)
-	charRegexp = []byte(`(\w+)`)
-	starRegexp = []byte(`(.*)`)
-)
+// findStatic continues find below the static node n
+func (n *muxNode) findStatic(rest string, m *muxMatch) *muxNode {
+	_, fixedMark := m.mark()
+	m.addFixed(n.segment)
+	if found := n.find(rest, m); found != nil {
+		return found
+	}
+	m.fixed = m.fixed[:fixedMark]
+	return nil
+}
 
-func mathPath(path string) (regPath string, keys []string) {
-	var (
-		replaceRegexp []byte
-		nPath         string
-	)
-	nSplit := strings.Split(path, "/")
-	// like {uid} or {uid:int}
-	// replace {uid} to regexp
-	replaceRegexp = charRegexp
-	wildcardRegexp := regexp.MustCompile(`{\w+}`)
-	for _, n := range nSplit {
-		if strings.Contains(n, "{") || strings.Contains(n, "*") {
-			// math {uid:int}
-			if strings.Contains(n, ":int") {
-				n = strings.ReplaceAll(n, ":int", "")
-				replaceRegexp = intRegexp
-			}
+// matchPattern returns the param values of a mixed segment, or nil
+func (n *muxNode) matchPattern(seg string, caseSensitive bool) []string {
+	re := n.regexp
+	if !caseSensitive {
+		re = n.foldRegexp
+	}
+	submatch := re.FindStringSubmatch(seg)
+	if submatch == nil {
+		return nil
+	}
+	values := make([]string, len(n.keys))
+	for i, group := range n.groups {
+		if !n.types[i].match(submatch[group]) {
+			return nil
+		}
+		values[i] = submatch[group]
+	}
+	return values
+}
 
-			// static /static/*filepath
-			if strings.Contains(n, "*filepath") {
-				n = strings.ReplaceAll(n, "*filepath", string(starRegexp))
-				replaceRegexp = starRegexp
-			}
+// compileMuxSegment compiles a single segment of the route path
+//	user           static
+//	{uid}          param, \w+
+//	{uid:int}      param, see param_type.go for all the types
+//	read_{id}.html pattern
+//	*filepath      catch-all
+func compileMuxSegment(seg, fullPath string, types paramTypes) *muxNode {
+	if seg[0] == '*' {
+		if len(seg) < 2 {
+			panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
+		}
+		return &muxNode{kind: muxCatchAll, segment: seg, key: seg[1:]}
+	}
+	if !strings.Contains(seg, "{") {
+		return &muxNode{kind: muxStatic, segment: seg}
+	}
 
-			// /*action
-			if strings.Contains(n, "*action") {
-				n = strings.ReplaceAll(n, "*action", string(starRegexp))
-				replaceRegexp = starRegexp
-			}
-			key := wildcardRegexp.FindAllString(n, -1)
-			keys = append(keys, key...)
-			nPath = string(wildcardRegexp.ReplaceAll([]byte(n), replaceRegexp))
+	// pure param, like {uid:int}
+	if seg[0] == '{' && closingBrace(seg) == len(seg)-1 {
+		key, typ := parseMuxParam(seg[1:len(seg)-1], fullPath, types)
+		return &muxNode{kind: muxParam, segment: seg, key: key, typ: typ}
+	}
 
-		} else {
-			nPath = n
+	// literals mixed with params, like read_{id:int}.html
+	n := &muxNode{kind: muxPattern, segment: seg}
+	var expr, foldExpr strings.Builder
+	writeLiteral := func(literal string) {
+		if literal != "" {
+			expr.WriteString(regexp.QuoteMeta(literal))
+			foldExpr.WriteString("(?i:" + regexp.QuoteMeta(literal) + ")")
+		}
+	}
+	rest := seg
+	for {
+		start := strings.IndexByte(rest, '{')
+		if start < 0 {
+			writeLiteral(rest)
+			break
 		}
-		regPath = regPath + nPath + "/"
+		end := closingBrace(rest[start:])
+		if end < 0 {
+			panic("missing '}' in segment '" + seg + "' in path '" + fullPath + "'")
+		}
+		end += start
+		writeLiteral(rest[:start])
+		key, typ := parseMuxParam(rest[start+1:end], fullPath, types)
+		// named groups keep the index right when a type pattern has groups itself
+		group := fmt.Sprintf("(?P<gow%d>%s)", len(n.keys), typ.pattern)
+		expr.WriteString(group)
+		foldExpr.WriteString(group)
+		n.keys = append(n.keys, key)
+		n.types = append(n.types, typ)
+		rest = rest[end+1:]
+	}
+	n.regexp = regexp.MustCompile("^" + expr.String() + "$")
+	n.foldRegexp = regexp.MustCompile("^" + foldExpr.String() + "$")
+	for i := range n.keys {
+		n.groups = append(n.groups, n.regexp.SubexpIndex(fmt.Sprintf("gow%d", i)))
 	}
+	return n
+}
 
-	//去掉group时可能产生的//
-	if strings.Contains(regPath, "//") {
-		regPath = strings.ReplaceAll(regPath, "//", "/")
+// parseMuxParam parses uid:int to its key and type
+func parseMuxParam(s, fullPath string, types paramTypes) (key string, typ *paramType) {
+	key, spec := s, ""
+	if i := strings.IndexByte(s, ':'); i >= 0 {
+		key, spec = s[:i], s[i+1:]
 	}
-	if regPath != "/" {
-		regPath = regPath[:len(regPath)-1]
+	if key == "" {
+		panic("wildcards must be named with a non-empty name in path '" + fullPath + "'")
 	}
-	return regPath, keys
+	return key, types.lookup(spec, fullPath)
 }
 
-func getNodeRouterPathMap(n *node) (rp RouterPath) {
-	rp = getNodeRouterPath("", rp, n)
-	return
+// closingBrace returns the index of the '}' closing the '{' at s[0], or -1
+func closingBrace(s string) int {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitMuxPath splits path into segments, empty segments are ignored
+func splitMuxPath(path string) []string {
+	var segments []string
+	for _, seg := range strings.Split(path, "/") {
+		if seg != "" {
+			segments = append(segments, seg)
+		}
+	}
+	return segments
+}
+
+func (m *muxMatch) mark() (int, int) {
+	if m.params == nil {
+		return 0, len(m.fixed)
+	}
+	return len(*m.params), len(m.fixed)
+}
+
+func (m *muxMatch) rollback(paramsMark, fixedMark int) {
+	if m.params != nil {
+		*m.params = (*m.params)[:paramsMark]
+	}
+	m.fixed = m.fixed[:fixedMark]
 }
 
-func getNodeRouterPath(path string, rp RouterPath, root *node) RouterPath {
-	path += root.path
-	if len(root.handlers) > 0 {
-		rp = append(rp, routerPathInfo{
-			Path:     strings.ToLower(path),
-			fullPath: root.fullPath,
-			handlers: root.handlers,
-			nType:    root.nType,
-		})
+func (m *muxMatch) addParam(key, value string) {
+	if m.params == nil {
+		return
+	}
+	if m.unescape {
+		if v, err := url.QueryUnescape(value); err == nil {
+			value = v
+		}
 	}
-	for _, child := range root.children {
-		rp = getNodeRouterPath(path, rp, child)
+	*m.params = append(*m.params, Param{Key: key, Value: value})
+}
+
+func (m *muxMatch) tracef(format string, args ...interface{}) {
+	*m.trace = append(*m.trace, fmt.Sprintf(format, args...))
+}
+
+func (m *muxMatch) addFixed(seg string) {
+	if m.fix {
+		m.fixed = append(m.fixed, seg)
 	}
-	return rp
 }
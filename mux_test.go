@@ -0,0 +1,452 @@
+package gow
+
+import (
+	"fmt"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newMuxTestEngine(paths ...string) *Engine {
+	r := New()
+	for _, p := range paths {
+		r.GET(p, func(c *Context) {})
+	}
+	return r
+}
+
+func TestMuxGetValue(t *testing.T) {
+	r := newMuxTestEngine(
+		"/",
+		"/user",
+		"/user/list",
+		"/user/{uid:int}",
+		"/user/{name}",
+		"/user/{name}/profile",
+		"/topic/{name}/{tid:int}",
+		"/read_{id:int}.html",
+		"/static/*filepath",
+	)
+	mux := r.trees.get("GET")
+
+	tests := []struct {
+		path     string
+		fullPath string
+		params   Params
+	}{
+		{"/", "/", nil},
+		{"/user", "/user", nil},
+		{"/user/", "/user", nil},
+		{"/user/list", "/user/list", nil},
+		{"/user/100", "/user/{uid:int}", Params{{"uid", "100"}}},
+		{"/user/sam", "/user/{name}", Params{{"name", "sam"}}},
+		{"/user/sam/profile", "/user/{name}/profile", Params{{"name", "sam"}}},
+		{"/topic/go/12", "/topic/{name}/{tid:int}", Params{{"name", "go"}, {"tid", "12"}}},
+		{"/read_100.html", "/read_{id:int}.html", Params{{"id", "100"}}},
+		{"/static/css/app.css", "/static/*filepath", Params{{"filepath", "/css/app.css"}}},
+		{"/topic/go/abc", "", nil},
+		{"/read_abc.html", "", nil},
+		{"/user/sam/other", "", nil},
+		{"/nothing", "", nil},
+	}
+	for _, tt := range tests {
+		params := make(Params, 0)
+		value := mux.getValue(tt.path, &params, false, false)
+		if value.fullPath != tt.fullPath {
+			t.Errorf("%s: fullPath = %q, want %q", tt.path, value.fullPath, tt.fullPath)
+			continue
+		}
+		if tt.fullPath == "" {
+			continue
+		}
+		if fmt.Sprint(*value.params) != fmt.Sprint(tt.params) {
+			t.Errorf("%s: params = %v, want %v", tt.path, *value.params, tt.params)
+		}
+	}
+}
+
+func TestMuxCaseSensitive(t *testing.T) {
+	r := newMuxTestEngine(
+		"/share/{code}",
+		"/User/{token}",
+		"/read_{id:int}.HTML",
+		"/files/*filepath",
+	)
+	mux := r.trees.get("GET")
+
+	tests := []struct {
+		path          string
+		caseSensitive bool
+		fullPath      string
+		params        Params
+	}{
+		{"/share/AbC9x", false, "/share/{code}", Params{{"code", "AbC9x"}}},
+		{"/SHARE/AbC9x", false, "/share/{code}", Params{{"code", "AbC9x"}}},
+		{"/user/Zm9vYmFy", false, "/User/{token}", Params{{"token", "Zm9vYmFy"}}},
+		{"/read_1.html", false, "/read_{id:int}.HTML", Params{{"id", "1"}}},
+		{"/files/Docs/A.PDF", false, "/files/*filepath", Params{{"filepath", "/Docs/A.PDF"}}},
+		{"/share/AbC9x", true, "/share/{code}", Params{{"code", "AbC9x"}}},
+		{"/SHARE/AbC9x", true, "", nil},
+		{"/User/Zm9vYmFy", true, "/User/{token}", Params{{"token", "Zm9vYmFy"}}},
+		{"/user/Zm9vYmFy", true, "", nil},
+		{"/read_1.HTML", true, "/read_{id:int}.HTML", Params{{"id", "1"}}},
+		{"/read_1.html", true, "", nil},
+		{"/FILES/Docs/A.PDF", true, "", nil},
+	}
+	for _, tt := range tests {
+		params := make(Params, 0)
+		value := mux.getValue(tt.path, &params, false, tt.caseSensitive)
+		if value.fullPath != tt.fullPath {
+			t.Errorf("%s (case sensitive %v): fullPath = %q, want %q", tt.path, tt.caseSensitive, value.fullPath, tt.fullPath)
+			continue
+		}
+		if tt.fullPath != "" && fmt.Sprint(*value.params) != fmt.Sprint(tt.params) {
+			t.Errorf("%s (case sensitive %v): params = %v, want %v", tt.path, tt.caseSensitive, *value.params, tt.params)
+		}
+	}
+
+	if fixed, ok := mux.fixPath("/USER/Zm9vYmFy"); !ok || fixed != "/User/Zm9vYmFy" {
+		t.Errorf("fixPath = %q, %v, want /User/Zm9vYmFy", fixed, ok)
+	}
+}
+
+func TestEngineCaseSensitiveRouting(t *testing.T) {
+	r := New()
+	r.CaseSensitiveRouting = true
+	r.RedirectFixedPath = true
+	var token string
+	r.GET("/share/{token}", func(c *Context) {
+		token = c.Param("token")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/share/QmFzZTY0", nil))
+	if w.Code != 200 || token != "QmFzZTY0" {
+		t.Errorf("code = %d, token = %q", w.Code, token)
+	}
+
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/SHARE/QmFzZTY0", nil))
+	if w.Code != 301 || w.Header().Get("Location") != "/share/QmFzZTY0" {
+		t.Errorf("code = %d, location = %q", w.Code, w.Header().Get("Location"))
+	}
+}
+
+func TestMuxAddRoutePanics(t *testing.T) {
+	paths := []string{
+		"/a/{id:unknown}",
+		"/a/*filepath/b",
+		"/a/{}",
+		"/a/x{id",
+		"/a/{id:int64(1)}",
+		"/a/{id:[a-z}",
+	}
+	for _, p := range paths {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s: expected panic", p)
+				}
+			}()
+			newMuxTree().addRoute(p, HandlersChain{func(c *Context) {}}, newParamTypes())
+		}()
+	}
+}
+
+func TestMuxParamTypes(t *testing.T) {
+	r := New()
+	r.RegisterParamType("objectid", ParamType{Pattern: `[0-9a-f]{24}`})
+	for _, p := range []string{
+		"/item/{id:int64(1,100)}",
+		"/item/{id:uuid}",
+		"/item/{id:objectid}",
+		`/item/{code:[a-z]{2}\d{2}}`,
+		"/item/{name:alpha}",
+		"/item/{slug:slug}",
+		"/report/{day:date}",
+		"/report/{period:enum(week|month)}",
+		"/report/{day:date}.csv",
+		"/report/{any}",
+	} {
+		r.GET(p, func(c *Context) {})
+	}
+	mux := r.trees.get("GET")
+
+	tests := []struct {
+		path     string
+		fullPath string
+	}{
+		{"/item/100", "/item/{id:int64(1,100)}"},
+		{"/item/101", "/item/{slug:slug}"},
+		{"/item/0f8fad5b-d9cb-469f-a165-70867728950e", "/item/{id:uuid}"},
+		{"/item/507f1f77bcf86cd799439011", "/item/{id:objectid}"},
+		{"/item/ab12", `/item/{code:[a-z]{2}\d{2}}`},
+		{"/item/abcd", "/item/{name:alpha}"},
+		{"/item/hello-gow", "/item/{slug:slug}"},
+		{"/item/hello_gow", ""},
+		{"/report/2021-03-09", "/report/{day:date}"},
+		{"/report/2021-13-09", ""},
+		{"/report/month", "/report/{period:enum(week|month)}"},
+		{"/report/year", "/report/{any}"},
+		{"/report/2021-03-09.csv", "/report/{day:date}.csv"},
+		{"/report/2021-13-09.csv", ""},
+	}
+	for _, tt := range tests {
+		if value := mux.getValue(tt.path, nil, false, false); value.fullPath != tt.fullPath {
+			t.Errorf("%s: fullPath = %q, want %q", tt.path, value.fullPath, tt.fullPath)
+		}
+	}
+}
+
+func TestParamTypeDisjoint(t *testing.T) {
+	types := newParamTypes()
+	tests := []struct {
+		a, b     string
+		disjoint bool
+	}{
+		{"int", "int64", false},
+		{"int", "int64(,-1)", true},
+		{"int64(1,10)", "int64(11,20)", true},
+		{"int64(1,10)", "int64(10,20)", false},
+		{"int", "slug", false},
+		{"int", "alpha", true},
+		{"int", "date", true},
+		{"alpha", "uuid", true},
+		{"slug", "uuid", false},
+		{"date", "alpha", true},
+		{"enum(1st|2nd)", "alpha", true},
+		{"enum(1st|2nd)", "slug", false},
+		{"enum(week|month)", "enum(day|year)", true},
+		{`[a-z]{2}\d{2}`, "int", true},
+		{`[a-z]{2}\d{2}`, "alpha", true},
+		{`(?i)ab`, "int", true},
+		{`(?i)ab`, "alpha", false},
+	}
+	for _, tt := range tests {
+		a, b := types.lookup(tt.a, "/"), types.lookup(tt.b, "/")
+		if got := a.disjoint(b); got != tt.disjoint {
+			t.Errorf("%s.disjoint(%s) = %v, want %v", tt.a, tt.b, got, tt.disjoint)
+		}
+		if got := b.disjoint(a); got != tt.disjoint {
+			t.Errorf("%s.disjoint(%s) = %v, want %v", tt.b, tt.a, got, tt.disjoint)
+		}
+	}
+}
+
+func TestContextParamValue(t *testing.T) {
+	r := New()
+	var (
+		id  interface{}
+		day time.Time
+		err error
+	)
+	r.GET("/item/{id:int64}/{day:date}", func(c *Context) {
+		id, _ = c.ParamValue("id")
+		day, err = c.ParamTime("day")
+	})
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/item/-12/2021-03-09", nil))
+	if id != int64(-12) {
+		t.Errorf("id = %#v, want int64(-12)", id)
+	}
+	if err != nil || day.Format(paramDateLayout) != "2021-03-09" {
+		t.Errorf("day = %v, %v", day, err)
+	}
+}
+
+var benchMuxPaths = []string{
+	"/api/v1/res1",
+	"/api/v1/res50/123",
+	"/api/v1/res99/123/items",
+	"/api/v1/res75/sam/detail",
+	"/api/v1/res0/read_100.html",
+	"/api/v1/notfound",
+}
+
+func newBenchMuxEngine() *Engine {
+	var paths []string
+	for i := 0; i < 100; i++ {
+		paths = append(paths,
+			fmt.Sprintf("/api/v1/res%d", i),
+			fmt.Sprintf("/api/v1/res%d/{id:int}", i),
+			fmt.Sprintf("/api/v1/res%d/{id:int}/items", i),
+			fmt.Sprintf("/api/v1/res%d/{name}/detail", i),
+		)
+	}
+	paths = append(paths, "/api/v1/res0/read_{id:int}.html")
+	return newMuxTestEngine(paths...)
+}
+
+func BenchmarkMuxCompiled(b *testing.B) {
+	r := newBenchMuxEngine()
+	mux := r.trees.get("GET")
+	params := make(Params, 0, r.maxParams)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		mux.getValue(benchMuxPaths[i%len(benchMuxPaths)], &params, false, false)
+	}
+}
+
+func BenchmarkMuxLegacyRegexp(b *testing.B) {
+	r := newBenchMuxEngine()
+	mux := r.trees.get("GET")
+	params := make(Params, 0, r.maxParams)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		legacyGetMuxValue(mux, benchMuxPaths[i%len(benchMuxPaths)], &params, false)
+	}
+}
+
+// legacy regexp matcher, kept as the baseline of the benchmarks above
+
+type legacyRouterPathInfo struct {
+	Path     string
+	fullPath string
+	handlers HandlersChain
+	params   *Params
+}
+
+func legacyGetMuxValue(t *muxTree, path string, params *Params, unescape bool) (value nodeValue) {
+	// the legacy matcher collected every route from the tree on each request
+	var rp []legacyRouterPathInfo
+	for _, n := range t.routes {
+		rp = append(rp, legacyRouterPathInfo{
+			Path:     strings.ToLower(n.fullPath),
+			fullPath: n.fullPath,
+			handlers: n.handlers,
+		})
+	}
+	routerPath, ok := legacyGetMatchPath(path, rp, unescape)
+	if ok {
+		if params != nil {
+			value.params = routerPath.params
+		}
+		value.handlers = routerPath.handlers
+		value.fullPath = routerPath.fullPath
+	}
+	return
+}
+
+func legacyGetMatchPath(path string, rp []legacyRouterPathInfo, unescape bool) (*legacyRouterPathInfo, bool) {
+	lastChar := path[len(path)-1:]
+	if path != "/" && lastChar == "/" && !strings.Contains(path, ".") {
+		path = path[:len(path)-1]
+	}
+	path = strings.ReplaceAll(path, "//", "/")
+	path = strings.ToLower(path)
+	for _, p := range rp {
+		regPath, keys := legacyMathPath(p.Path)
+		if path == regPath {
+			return &p, true
+		}
+		ok, _ := regexp.MatchString("^"+regPath+"$", path)
+		if ok {
+			valueRegexp := regexp.MustCompile(regPath)
+			if unescape {
+				if v, err := url.QueryUnescape(path); err == nil {
+					path = v
+				}
+			}
+			values := valueRegexp.FindStringSubmatch(path)
+			params := new(Params)
+			for i, k := range keys {
+				*params = append(*params, Param{
+					Key:   strings.ReplaceAll(strings.ReplaceAll(k, "{", ""), "}", ""),
+					Value: values[i+1],
+				})
+			}
+			p.params = params
+			return &p, ok
+		}
+	}
+	return nil, false
+}
+
+func legacyMathPath(path string) (regPath string, keys []string) {
+	var nPath string
+	replaceRegexp := `(\w+)`
+	wildcardRegexp := regexp.MustCompile(`{\w+}`)
+	for _, n := range strings.Split(path, "/") {
+		if strings.Contains(n, "{") || strings.Contains(n, "*") {
+			if strings.Contains(n, ":int") {
+				n = strings.ReplaceAll(n, ":int", "")
+				replaceRegexp = `(\d+)`
+			}
+			if strings.Contains(n, "*filepath") {
+				n = strings.ReplaceAll(n, "*filepath", `(.*)`)
+				replaceRegexp = `(.*)`
+			}
+			keys = append(keys, wildcardRegexp.FindAllString(n, -1)...)
+			nPath = wildcardRegexp.ReplaceAllString(n, replaceRegexp)
+		} else {
+			nPath = n
+		}
+		regPath = regPath + nPath + "/"
+	}
+	regPath = strings.ReplaceAll(regPath, "//", "/")
+	if regPath != "/" {
+		regPath = regPath[:len(regPath)-1]
+	}
+	return regPath, keys
+}
+
+func TestMuxRouteConflict(t *testing.T) {
+	conflicts := [][2]string{
+		{"/user/{id}", "/user/{name}"},
+		{"/user/{id:int}/posts", "/user/{uid:int}/posts"},
+		{"/topic/read_{id:int}.html", "/topic/read_{tid:int}.html"},
+		{"/files/*filepath", "/files/*path"},
+		{"/user/{id:int64}", "/user/{id:int}"},
+		{"/user/{id:int64(1,10)}", "/user/{id:int64(5,20)}"},
+		{"/s/{s:slug}", "/s/{id:int}"},
+		{"/rank/{n:enum(1st|2nd)}", "/rank/{s:slug}"},
+	}
+	for _, paths := range conflicts {
+		r := New()
+		r.PanicOnRouteConflict = true
+		r.GET(paths[0], func(c *Context) {})
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("%s after %s did not panic", paths[1], paths[0])
+				}
+			}()
+			r.GET(paths[1], func(c *Context) {})
+		}()
+	}
+
+	r := New()
+	r.PanicOnRouteConflict = true
+	for _, p := range []string{"/user/{id:int64(1,10)}", "/user/{name}", "/user/{id:int64(11,20)}", "/user/{id:int64(,-1)}",
+		"/user/{name:alpha}", "/user/{rank:enum(1st|2nd)}", "/user/list"} {
+		r.GET(p, func(c *Context) {})
+	}
+	r.POST("/user/{uid}", func(c *Context) {})
+}
+
+func TestEngineExplainRoute(t *testing.T) {
+	r := newMuxTestEngine("/user/{uid:int}", "/user/{name}", "/user/{nick}")
+
+	e := r.ExplainRoute("GET", "/user/sam")
+	if e.Route != "/user/{name}" || e.Params.ByName("name") != "sam" {
+		t.Errorf("route = %q, params = %v", e.Route, e.Params)
+	}
+	var rejected, shadowed bool
+	for _, step := range e.Steps {
+		rejected = rejected || strings.Contains(step, "does not match param '{uid:int}'")
+		shadowed = shadowed || strings.Contains(step, "/user/{nick}")
+	}
+	if !rejected || !shadowed {
+		t.Errorf("steps = %q", e.Steps)
+	}
+
+	if e := r.ExplainRoute("POST", "/user/1"); e.Route != "" || len(e.Steps) != 2 {
+		t.Errorf("POST explanation = %s", e)
+	}
+}
@@ -0,0 +1,128 @@
+/*
+content negotiation
+	the response format is chosen by the Accept header, or by c.Accepted when it is set
+	c.Negotiate(200, gow.NegotiateConfig{Offered: []string{gow.MIMEJSON, gow.MIMEXML}, Data: user})
+	format := c.NegotiateFormat(gow.MIMEJSON, gow.MIMEXML)
+*/
+
+package gow
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MIME types offered to Negotiate
+const (
+	MIMEJSON = "application/json"
+	MIMEXML  = "application/xml"
+	MIMEXML2 = "text/xml"
+	MIMEYAML = "application/x-yaml"
+	MIMEHTML = "text/html"
+)
+
+var errNotAcceptable = errors.New("the accepted formats are not offered by the server")
+
+// NegotiateConfig the formats offered by Negotiate and the data for each of them
+//	Data is used when the format has no own data,
+//	Custom writes the response for an offered format other than the built-in ones
+type NegotiateConfig struct {
+	Offered  []string
+	HTMLName string
+	HTMLData interface{}
+	JSONData interface{}
+	XMLData  interface{}
+	YAMLData interface{}
+	Data     interface{}
+	Custom   func(format string)
+}
+
+// Negotiate write the response in the format the client accepts best
+//	responds 406 when none of the offered formats is acceptable
+//	c.Negotiate(200, gow.NegotiateConfig{
+//		Offered:  []string{gow.MIMEHTML, gow.MIMEJSON, gow.MIMEXML},
+//		HTMLName: "user.html",
+//		Data:     user,
+//	})
+//	c.Negotiate(200, gow.NegotiateConfig{
+//		Offered: []string{gow.MIMEJSON, "text/csv"},
+//		Data:    users,
+//		Custom:  func(format string) { c.Header("Content-Type", format); c.Status(200); c.Writer.Write(csv) },
+//	})
+func (c *Context) Negotiate(code int, config NegotiateConfig) {
+	if config.Custom == nil {
+		for _, offer := range config.Offered {
+			assert1(builtinMIME(offer), "the offered format "+offer+" needs a NegotiateConfig.Custom")
+		}
+	}
+	switch format := c.NegotiateFormat(config.Offered...); {
+	case format == "":
+		c.AbortWithError(http.StatusNotAcceptable, errNotAcceptable)
+	case !builtinMIME(format):
+		config.Custom(format)
+	case format == MIMEJSON:
+		c.ServerJSON(code, chooseData(config.JSONData, config.Data))
+	case format == MIMEXML, format == MIMEXML2:
+		c.ServerXML(code, chooseData(config.XMLData, config.Data))
+	case format == MIMEYAML:
+		c.ServerYAML(code, chooseData(config.YAMLData, config.Data))
+	default:
+		c.ServerHTML(code, config.HTMLName, chooseData(config.HTMLData, config.Data))
+	}
+}
+
+// builtinMIME report whether Negotiate can write the format by itself
+func builtinMIME(format string) bool {
+	switch format {
+	case MIMEJSON, MIMEXML, MIMEXML2, MIMEYAML, MIMEHTML:
+		return true
+	}
+	return false
+}
+
+// NegotiateFormat return the offered format the client accepts best
+//	c.Accepted is used instead of the Accept header when it is set,
+//	returns the first offered format when the request has no Accept header,
+//	returns "" when nothing is acceptable
+func (c *Context) NegotiateFormat(offered ...string) string {
+	assert1(len(offered) > 0, "you must provide at least one offer")
+
+	accepted, refused := c.Accepted, []string(nil)
+	if accepted == nil {
+		accepted, refused = parseAcceptQ(c.GetHeader("Accept"))
+	}
+	if len(accepted) == 0 && len(refused) == 0 {
+		return offered[0]
+	}
+	for _, accept := range accepted {
+		for _, offer := range offered {
+			if matchMIME(accept, offer) && !refusedMIME(refused, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// refusedMIME report whether the offer is refused explicitly with q=0
+func refusedMIME(refused []string, offer string) bool {
+	for _, r := range refused {
+		if strings.EqualFold(r, offer) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchMIME report whether the offer matches the accepted type,
+// the accepted type may be */* or type/*
+func matchMIME(accept, offer string) bool {
+	if accept == "*/*" || strings.EqualFold(accept, offer) {
+		return true
+	}
+	if strings.HasSuffix(accept, "/*") {
+		return strings.HasPrefix(strings.ToLower(offer), strings.ToLower(accept[:len(accept)-1]))
+	}
+	return false
+}
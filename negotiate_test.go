@@ -0,0 +1,87 @@
+package gow
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type negotiateTestUser struct {
+	Name string
+}
+
+func TestContextNegotiate(t *testing.T) {
+	r := New()
+	r.GET("/user", func(c *Context) {
+		c.Negotiate(200, NegotiateConfig{
+			Offered: []string{MIMEJSON, MIMEXML, MIMEYAML},
+			Data:    H{"name": "sam"},
+			XMLData: negotiateTestUser{"sam"},
+		})
+	})
+
+	tests := []struct {
+		accept      string
+		code        int
+		contentType string
+	}{
+		{"", 200, ContentJSON},
+		{"application/xml", 200, ContentXML},
+		{"text/html, application/xml;q=0.9, */*;q=0.8", 200, ContentXML},
+		{"application/json;q=0.5, application/x-yaml", 200, ContentYAML},
+		{"text/*, application/*;q=0.2", 200, ContentJSON},
+		{"application/json;q=0, */*", 200, ContentXML},
+		{"text/html", 406, ""},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "/user", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		if w.Code != tt.code || w.Header().Get("Content-Type") != tt.contentType {
+			t.Errorf("%q: code = %d, type = %q, want %d, %q", tt.accept, w.Code, w.Header().Get("Content-Type"), tt.code, tt.contentType)
+		}
+		if tt.contentType == ContentXML && !strings.Contains(w.Body.String(), "<Name>sam</Name>") {
+			t.Errorf("%q: body = %q", tt.accept, w.Body.String())
+		}
+	}
+
+	c := &Context{Request: httptest.NewRequest("GET", "/", nil), Accepted: []string{MIMEYAML}}
+	c.Request.Header.Set("Accept", MIMEJSON)
+	if f := c.NegotiateFormat(MIMEJSON, MIMEYAML); f != MIMEYAML {
+		t.Errorf("format = %q, want c.Accepted %q", f, MIMEYAML)
+	}
+}
+
+func TestContextNegotiateCustom(t *testing.T) {
+	r := New()
+	r.GET("/user", func(c *Context) {
+		c.Negotiate(200, NegotiateConfig{
+			Offered: []string{MIMEJSON, "text/csv"},
+			Data:    H{"name": "sam"},
+			Custom: func(format string) {
+				c.Header("Content-Type", format)
+				c.Status(200)
+				c.Writer.Write([]byte("name\nsam\n"))
+			},
+		})
+	})
+
+	req := httptest.NewRequest("GET", "/user", nil)
+	req.Header.Set("Accept", "text/csv")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 200 || w.Header().Get("Content-Type") != "text/csv" || w.Body.String() != "name\nsam\n" {
+		t.Errorf("code = %d, type = %q, body = %q", w.Code, w.Header().Get("Content-Type"), w.Body.String())
+	}
+
+	c := &Context{Request: httptest.NewRequest("GET", "/", nil)}
+	defer func() {
+		if recover() == nil {
+			t.Error("an offer outside the built-in formats without Custom did not panic")
+		}
+	}()
+	c.Negotiate(200, NegotiateConfig{Offered: []string{"text/csv"}})
+}
@@ -0,0 +1,364 @@
+/*
+openapi 3 document of the registered routes
+	r.GET("/user/{uid:int}", handler).Meta(gow.RouteMeta{
+		Description: "user detail",
+		Tags:        []string{"user"},
+		Response:    User{},
+	})
+	r.ServeOpenAPI("/openapi.json", gow.OpenAPIInfo{Title: "user api", Version: "1.0"})
+	r.ServeOpenAPI("/openapi.yaml", gow.OpenAPIInfo{Title: "user api", Version: "1.0"})
+path params are derived from {uid:int},
+responses are wrapped in DataResponse unless RouteMeta.RawResponse is true,
+host routes and hidden routes are left out
+*/
+
+package gow
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const openAPIVersion = "3.0.3"
+
+// OpenAPIInfo is the info object of the openapi document
+type OpenAPIInfo struct {
+	Title       string `json:"title" yaml:"title"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Version     string `json:"version" yaml:"version"`
+}
+
+// OpenAPIDoc is an openapi 3 document, it is marshaled by encoding/json or yaml
+type OpenAPIDoc struct {
+	OpenAPI    string                                  `json:"openapi" yaml:"openapi"`
+	Info       OpenAPIInfo                             `json:"info" yaml:"info"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths" yaml:"paths"`
+	Components *openAPIComponents                      `json:"components,omitempty" yaml:"components,omitempty"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*openAPISchema         `json:"schemas,omitempty" yaml:"schemas,omitempty"`
+	SecuritySchemes map[string]*openAPISecurityScheme `json:"securitySchemes,omitempty" yaml:"securitySchemes,omitempty"`
+}
+
+type openAPISecurityScheme struct {
+	Type string `json:"type" yaml:"type"`
+	In   string `json:"in" yaml:"in"`
+	Name string `json:"name" yaml:"name"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty" yaml:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty" yaml:"summary,omitempty"`
+	Tags        []string                    `json:"tags,omitempty" yaml:"tags,omitempty"`
+	Deprecated  bool                        `json:"deprecated,omitempty" yaml:"deprecated,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty" yaml:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty" yaml:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses" yaml:"responses"`
+	Security    []map[string][]string       `json:"security,omitempty" yaml:"security,omitempty"`
+}
+
+type openAPIParameter struct {
+	Name     string         `json:"name" yaml:"name"`
+	In       string         `json:"in" yaml:"in"`
+	Required bool           `json:"required" yaml:"required"`
+	Schema   *openAPISchema `json:"schema" yaml:"schema"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                         `json:"required" yaml:"required"`
+	Content  map[string]*openAPIMediaType `json:"content" yaml:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                       `json:"description" yaml:"description"`
+	Content     map[string]*openAPIMediaType `json:"content,omitempty" yaml:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *openAPISchema `json:"schema" yaml:"schema"`
+}
+
+type openAPISchema struct {
+	Ref                  string                    `json:"$ref,omitempty" yaml:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty" yaml:"type,omitempty"`
+	Format               string                    `json:"format,omitempty" yaml:"format,omitempty"`
+	Pattern              string                    `json:"pattern,omitempty" yaml:"pattern,omitempty"`
+	Enum                 []string                  `json:"enum,omitempty" yaml:"enum,omitempty"`
+	Minimum              *int64                    `json:"minimum,omitempty" yaml:"minimum,omitempty"`
+	Maximum              *int64                    `json:"maximum,omitempty" yaml:"maximum,omitempty"`
+	Items                *openAPISchema            `json:"items,omitempty" yaml:"items,omitempty"`
+	Properties           map[string]*openAPISchema `json:"properties,omitempty" yaml:"properties,omitempty"`
+	AdditionalProperties *openAPISchema            `json:"additionalProperties,omitempty" yaml:"additionalProperties,omitempty"`
+}
+
+// OpenAPI builds the openapi document of the registered routes
+func (engine *Engine) OpenAPI(info OpenAPIInfo) *OpenAPIDoc {
+	if info.Title == "" {
+		info.Title = engine.AppName
+	}
+	if info.Version == "" {
+		info.Version = "1.0.0"
+	}
+	doc := &OpenAPIDoc{
+		OpenAPI: openAPIVersion,
+		Info:    info,
+		Paths:   make(map[string]map[string]*openAPIOperation),
+	}
+	schemas := make(map[string]*openAPISchema)
+	securitySchemes := make(map[string]*openAPISecurityScheme)
+
+	for _, route := range engine.Routes() {
+		if route.Host != "" || route.Listener != "" || route.Method == http.MethodConnect {
+			continue
+		}
+		var meta RouteMeta
+		if route.Meta != nil {
+			meta = *route.Meta
+		}
+		if meta.Hidden {
+			continue
+		}
+
+		path, params := openAPIPath(route.Path, engine.routeParamTypes(route.Method, route.Path))
+		op := &openAPIOperation{
+			OperationID: route.Name,
+			Summary:     meta.Description,
+			Tags:        meta.Tags,
+			Deprecated:  meta.Deprecated,
+			Parameters:  params,
+			Responses:   make(map[string]*openAPIResponse),
+		}
+		if meta.Request != nil {
+			op.RequestBody = &openAPIRequestBody{
+				Required: true,
+				Content: map[string]*openAPIMediaType{
+					"application/json": {Schema: openAPISchemaOf(reflect.TypeOf(meta.Request), schemas)},
+				},
+			}
+		}
+		if meta.Auth != "" {
+			op.Security = []map[string][]string{{meta.Auth: {}}}
+			securitySchemes[meta.Auth] = &openAPISecurityScheme{Type: "apiKey", In: "header", Name: "Authorization"}
+		}
+
+		var schema *openAPISchema
+		if meta.RawResponse {
+			if meta.Response != nil {
+				schema = openAPISchemaOf(reflect.TypeOf(meta.Response), schemas)
+			}
+		} else {
+			schema = openAPIDataResponse(meta.Response, schemas)
+		}
+		resp := &openAPIResponse{Description: "success"}
+		if schema != nil {
+			resp.Content = map[string]*openAPIMediaType{"application/json": {Schema: schema}}
+		}
+		op.Responses["200"] = resp
+
+		if doc.Paths[path] == nil {
+			doc.Paths[path] = make(map[string]*openAPIOperation)
+		}
+		doc.Paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	if len(schemas) > 0 || len(securitySchemes) > 0 {
+		doc.Components = &openAPIComponents{Schemas: schemas, SecuritySchemes: securitySchemes}
+	}
+	return doc
+}
+
+// ServeOpenAPI serves the openapi document at path,
+// it is written as yaml when path ends with .yaml or .yml, or as json
+//	r.ServeOpenAPI("/openapi.json", gow.OpenAPIInfo{Title: "user api", Version: "1.0"})
+func (engine *Engine) ServeOpenAPI(path string, info OpenAPIInfo) {
+	asYAML := strings.HasSuffix(path, ".yaml") || strings.HasSuffix(path, ".yml")
+	engine.GET(path, func(c *Context) {
+		doc := engine.OpenAPI(info)
+		if asYAML {
+			c.YAML(doc)
+			return
+		}
+		c.JSON(doc)
+	}).Meta(RouteMeta{Hidden: true})
+}
+
+// routeParamTypes returns the param types of a default route
+func (engine *Engine) routeParamTypes(method, path string) map[string]*paramType {
+	root := engine.trees.get(method)
+	if root == nil {
+		return nil
+	}
+	for _, n := range root.routes {
+		if n.fullPath == path {
+			return n.paramTypes
+		}
+	}
+	return nil
+}
+
+// openAPIPath converts a route path to an openapi path and its params,
+// like /topic/{name}/read_{tid:int}.html to /topic/{name}/read_{tid}.html
+func openAPIPath(fullPath string, types map[string]*paramType) (string, []*openAPIParameter) {
+	var (
+		buf    strings.Builder
+		params []*openAPIParameter
+	)
+	for _, seg := range splitMuxPath(fullPath) {
+		buf.WriteByte('/')
+		if seg[0] == '*' {
+			buf.WriteString("{" + seg[1:] + "}")
+			params = append(params, &openAPIParameter{Name: seg[1:], In: "path", Required: true, Schema: &openAPISchema{Type: "string"}})
+			continue
+		}
+		rest := seg
+		for {
+			start := strings.IndexByte(rest, '{')
+			if start < 0 {
+				buf.WriteString(rest)
+				break
+			}
+			end := start + closingBrace(rest[start:])
+			key, spec := rest[start+1:end], ""
+			if i := strings.IndexByte(key, ':'); i >= 0 {
+				key, spec = key[:i], key[i+1:]
+			}
+			buf.WriteString(rest[:start] + "{" + key + "}")
+			params = append(params, &openAPIParameter{Name: key, In: "path", Required: true, Schema: openAPIParamSchema(spec, types[key])})
+			rest = rest[end+1:]
+		}
+	}
+	if buf.Len() == 0 || strings.HasSuffix(fullPath, "/") {
+		buf.WriteByte('/')
+	}
+	return buf.String(), params
+}
+
+// openAPIParamSchema returns the schema of a path param typed by spec
+func openAPIParamSchema(spec string, typ *paramType) *openAPISchema {
+	switch {
+	case spec == "":
+		return &openAPISchema{Type: "string"}
+	case spec == "int":
+		return &openAPISchema{Type: "integer"}
+	case spec == "int64":
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case spec == "uuid":
+		return &openAPISchema{Type: "string", Format: "uuid"}
+	case spec == "date":
+		return &openAPISchema{Type: "string", Format: "date"}
+	case strings.HasPrefix(spec, "int64(") && strings.HasSuffix(spec, ")"):
+		schema := &openAPISchema{Type: "integer", Format: "int64"}
+		bounds := strings.Split(spec[len("int64("):len(spec)-1], ",")
+		if len(bounds) == 2 {
+			if v, err := strconv.ParseInt(strings.TrimSpace(bounds[0]), 10, 64); err == nil {
+				schema.Minimum = &v
+			}
+			if v, err := strconv.ParseInt(strings.TrimSpace(bounds[1]), 10, 64); err == nil {
+				schema.Maximum = &v
+			}
+		}
+		return schema
+	case strings.HasPrefix(spec, "enum(") && strings.HasSuffix(spec, ")"):
+		return &openAPISchema{Type: "string", Enum: strings.Split(spec[len("enum("):len(spec)-1], "|")}
+	}
+	schema := &openAPISchema{Type: "string"}
+	if typ != nil {
+		schema.Pattern = "^(?:" + typ.pattern + ")$"
+	}
+	return schema
+}
+
+// openAPIDataResponse returns the schema of DataResponse with data in its body
+func openAPIDataResponse(data interface{}, schemas map[string]*openAPISchema) *openAPISchema {
+	body := openAPIStructSchema(reflect.TypeOf(Body{}), schemas)
+	if data != nil {
+		body.Properties["data"] = openAPISchemaOf(reflect.TypeOf(data), schemas)
+	}
+	resp := openAPIStructSchema(reflect.TypeOf(DataResponse{}), schemas)
+	resp.Properties["body"] = body
+	return resp
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// openAPISchemaOf returns the schema of t, named structs are added to schemas and referenced
+func openAPISchemaOf(t reflect.Type, schemas map[string]*openAPISchema) *openAPISchema {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &openAPISchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Uint, reflect.Uint8, reflect.Uint16:
+		return &openAPISchema{Type: "integer"}
+	case reflect.Int32, reflect.Uint32:
+		return &openAPISchema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &openAPISchema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &openAPISchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &openAPISchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &openAPISchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &openAPISchema{Type: "string", Format: "byte"}
+		}
+		return &openAPISchema{Type: "array", Items: openAPISchemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return &openAPISchema{Type: "object", AdditionalProperties: openAPISchemaOf(t.Elem(), schemas)}
+	case reflect.Struct:
+		if t == timeType {
+			return &openAPISchema{Type: "string", Format: "date-time"}
+		}
+		if t.Name() == "" {
+			return openAPIStructSchema(t, schemas)
+		}
+		ref := &openAPISchema{Ref: "#/components/schemas/" + t.Name()}
+		if _, ok := schemas[t.Name()]; !ok {
+			// added before its fields, so a recursive type refers to itself
+			schemas[t.Name()] = &openAPISchema{}
+			schemas[t.Name()] = openAPIStructSchema(t, schemas)
+		}
+		return ref
+	}
+	return &openAPISchema{}
+}
+
+// openAPIStructSchema returns the inline schema of the struct t, named by its json tags
+func openAPIStructSchema(t reflect.Type, schemas map[string]*openAPISchema) *openAPISchema {
+	schema := &openAPISchema{Type: "object", Properties: make(map[string]*openAPISchema)}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				for k, v := range openAPIStructSchema(ft, schemas).Properties {
+					schema.Properties[k] = v
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		schema.Properties[name] = openAPISchemaOf(field.Type, schemas)
+	}
+	return schema
+}
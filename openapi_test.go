@@ -0,0 +1,90 @@
+package gow
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type openAPITestUser struct {
+	ID      int64              `json:"id"`
+	Name    string             `json:"name"`
+	Friends []*openAPITestUser `json:"friends,omitempty"`
+	secret  string
+}
+
+func TestEngineOpenAPI(t *testing.T) {
+	r := New()
+	h := func(c *Context) {}
+	r.GET("/user/{uid:int}", h).Name("user.show").Meta(RouteMeta{
+		Description: "user detail",
+		Tags:        []string{"user"},
+		Auth:        "token",
+		Response:    openAPITestUser{},
+	})
+	r.POST("/user", h).Meta(RouteMeta{Request: openAPITestUser{}, RawResponse: true})
+	r.GET("/topic/{type:enum(go|js)}/read_{tid:int64(1,)}.html", h)
+	r.GET("/files/*filepath", h)
+	r.Host("{tenant}.example.com").GET("/", h)
+	r.ServeOpenAPI("/openapi.json", OpenAPIInfo{Title: "test", Version: "1.0"})
+	r.ServeOpenAPI("/openapi.yaml", OpenAPIInfo{Title: "test", Version: "1.0"})
+
+	doc := r.OpenAPI(OpenAPIInfo{})
+	if doc.Info.Title != r.AppName {
+		t.Errorf("title = %q, want %q", doc.Info.Title, r.AppName)
+	}
+	if len(doc.Paths) != 4 {
+		t.Errorf("paths = %d, want 4", len(doc.Paths))
+	}
+
+	op := doc.Paths["/user/{uid}"]["get"]
+	if op == nil {
+		t.Fatalf("GET /user/{uid} is missing")
+	}
+	if op.OperationID != "user.show" || op.Summary != "user detail" || len(op.Security) != 1 {
+		t.Errorf("operation = %+v", op)
+	}
+	if len(op.Parameters) != 1 || op.Parameters[0].Schema.Type != "integer" {
+		t.Errorf("params = %+v", op.Parameters)
+	}
+	body := op.Responses["200"].Content["application/json"].Schema.Properties["body"]
+	if body.Properties["pager"].Ref != "#/components/schemas/Pager" ||
+		body.Properties["data"].Ref != "#/components/schemas/openAPITestUser" {
+		t.Errorf("response body = %+v", body)
+	}
+	user := doc.Components.Schemas["openAPITestUser"]
+	if user == nil || len(user.Properties) != 3 || user.Properties["friends"].Items.Ref == "" {
+		t.Errorf("user schema = %+v", user)
+	}
+	if pager := doc.Components.Schemas["Pager"]; pager == nil || pager.Properties["limit"] != nil {
+		t.Errorf("pager schema = %+v", pager)
+	}
+
+	post := doc.Paths["/user"]["post"]
+	if post.RequestBody == nil || post.Responses["200"].Content != nil {
+		t.Errorf("post operation = %+v", post)
+	}
+
+	topic := doc.Paths["/topic/{type}/read_{tid}.html"]["get"]
+	if topic == nil || len(topic.Parameters) != 2 ||
+		len(topic.Parameters[0].Schema.Enum) != 2 ||
+		*topic.Parameters[1].Schema.Minimum != 1 || topic.Parameters[1].Schema.Maximum != nil {
+		t.Errorf("topic operation = %+v", topic)
+	}
+	if doc.Paths["/files/{filepath}"] == nil {
+		t.Errorf("/files/{filepath} is missing")
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	var served map[string]interface{}
+	if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil || served["openapi"] != openAPIVersion {
+		t.Errorf("/openapi.json = %s, %v", w.Body.String(), err)
+	}
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.yaml", nil))
+	if !strings.Contains(w.Body.String(), "openapi: 3.0.3") || !strings.Contains(w.Body.String(), "$ref:") {
+		t.Errorf("/openapi.yaml = %s", w.Body.String())
+	}
+}
@@ -0,0 +1,384 @@
+/*
+typed route params
+	{uid}                \w+
+	{uid:int}            \d+
+	{id:int64}           -?\d+, parsed to int64
+	{page:int64(1,100)}  int64 in [1,100], either bound can be empty
+	{id:uuid}            8-4-4-4-12 hex uuid
+	{name:alpha}         [A-Za-z]+
+	{title:slug}         hello-world
+	{day:date}           2006-01-02, parsed to time.Time
+	{type:enum(a|b|c)}   one of a, b and c
+	{code:[A-Z]{3}}      inline regexp
+a value failing its type falls through to the next route, or 404
+*/
+
+package gow
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const paramDateLayout = "2006-01-02"
+
+// ParamType defines a custom route param type used as {name:type}
+type ParamType struct {
+	// Pattern is the regexp a value must match, like `[0-9a-f]{24}`
+	Pattern string
+
+	// Parse is optional, it converts a matched value for Context.ParamValue.
+	// A value it fails to parse does not match the route.
+	Parse func(value string) (interface{}, error)
+}
+
+// paramType is a compiled route param type
+type paramType struct {
+	// pattern is used when the param is mixed with literals in one segment
+	pattern string
+	// match checks a whole value
+	match func(string) bool
+	// parse converts a matched value, nil keeps the string
+	parse func(string) (interface{}, error)
+
+	// ranged types match integers in [min,max], enum types only the values of enum,
+	// they are used by disjoint
+	ranged   bool
+	min, max int64
+	enum     []string
+}
+
+// paramTypes is the route param type registry of an engine
+type paramTypes map[string]*paramType
+
+// newParamTypes returns the built-in param types
+func newParamTypes() paramTypes {
+	return paramTypes{
+		"":      {pattern: `\w+`, match: isWordString},
+		"int":   {pattern: `\d+`, match: isDigitString, parse: parseParamInt, ranged: true, max: math.MaxInt64},
+		"alpha": {pattern: `[A-Za-z]+`, match: isAlphaString},
+		"int64": newInt64RangeParamType(",", "int64"),
+		"uuid":  mustCompileParamType(`[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}`, nil),
+		"slug":  mustCompileParamType(`[A-Za-z0-9]+(?:-[A-Za-z0-9]+)*`, nil),
+		"date":  mustCompileParamType(`\d{4}-\d{2}-\d{2}`, parseParamDate),
+	}
+}
+
+// RegisterParamType registers a custom route param type.
+// It must be called before the routes using the type are registered.
+//	r.RegisterParamType("objectid", gow.ParamType{Pattern: `[0-9a-f]{24}`})
+//	r.GET("/article/{id:objectid}", handler)
+func (engine *Engine) RegisterParamType(name string, typ ParamType) {
+	assert1(isWordString(name), "param type name must match \\w+, has: '"+name+"'")
+	engine.paramTypes[name] = mustCompileParamType(typ.Pattern, typ.Parse)
+}
+
+// lookup returns the type of the spec after ':' in {name:spec}
+func (types paramTypes) lookup(spec, fullPath string) *paramType {
+	if typ, ok := types[spec]; ok {
+		return typ
+	}
+
+	// types with arguments, like int64(1,100) and enum(a|b)
+	if i := strings.IndexByte(spec, '('); i > 0 && spec[len(spec)-1] == ')' && isWordString(spec[:i]) {
+		name, args := spec[:i], spec[i+1:len(spec)-1]
+		switch name {
+		case "int64":
+			return newInt64RangeParamType(args, fullPath)
+		case "enum":
+			return newEnumParamType(args, fullPath)
+		}
+	}
+
+	if isWordString(spec) {
+		panic("unknown param type '" + spec + "' in path '" + fullPath + "'")
+	}
+
+	// inline regexp, like [A-Z]{3}
+	typ, err := compileParamType(spec, nil)
+	if err != nil {
+		panic("invalid param regexp '" + spec + "' in path '" + fullPath + "': " + err.Error())
+	}
+	return typ
+}
+
+func compileParamType(pattern string, parse func(string) (interface{}, error)) (*paramType, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("param type pattern can not be empty")
+	}
+	re, err := regexp.Compile("^(?:" + pattern + ")$")
+	if err != nil {
+		return nil, err
+	}
+	return &paramType{
+		pattern: pattern,
+		match: func(s string) bool {
+			if !re.MatchString(s) {
+				return false
+			}
+			if parse != nil {
+				_, err := parse(s)
+				return err == nil
+			}
+			return true
+		},
+		parse: parse,
+	}, nil
+}
+
+func mustCompileParamType(pattern string, parse func(string) (interface{}, error)) *paramType {
+	typ, err := compileParamType(pattern, parse)
+	if err != nil {
+		panic("invalid param type pattern '" + pattern + "': " + err.Error())
+	}
+	return typ
+}
+
+// newInt64RangeParamType returns the type of int64(min,max)
+func newInt64RangeParamType(args, fullPath string) *paramType {
+	bounds := strings.Split(args, ",")
+	if len(bounds) != 2 {
+		panic("int64 range must be like int64(min,max) in path '" + fullPath + "'")
+	}
+	parseBound := func(s string, def int64) int64 {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			return def
+		}
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			panic("invalid int64 range bound '" + s + "' in path '" + fullPath + "'")
+		}
+		return v
+	}
+	min := parseBound(bounds[0], math.MinInt64)
+	max := parseBound(bounds[1], math.MaxInt64)
+	typ := mustCompileParamType(`-?\d+`, func(s string) (interface{}, error) {
+		v, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		if v < min || v > max {
+			return nil, fmt.Errorf("%d is out of range [%d,%d]", v, min, max)
+		}
+		return v, nil
+	})
+	typ.ranged, typ.min, typ.max = true, min, max
+	return typ
+}
+
+// newEnumParamType returns the type of enum(a|b|c)
+func newEnumParamType(args, fullPath string) *paramType {
+	options := strings.Split(args, "|")
+	set := make(map[string]bool, len(options))
+	quoted := make([]string, 0, len(options))
+	for _, option := range options {
+		if option == "" {
+			panic("enum options can not be empty in path '" + fullPath + "'")
+		}
+		set[option] = true
+		quoted = append(quoted, regexp.QuoteMeta(option))
+	}
+	return &paramType{
+		pattern: "(?:" + strings.Join(quoted, "|") + ")",
+		match: func(s string) bool {
+			return set[s]
+		},
+		enum: options,
+	}
+}
+
+// disjoint reports whether no value matches both types.
+// It is known for enums, integer ranges, and the types whose patterns share no char,
+// or one of them requires a char the other never matches, like date and int.
+func (typ *paramType) disjoint(other *paramType) bool {
+	if typ == other {
+		return false
+	}
+	if typ.enum != nil || other.enum != nil {
+		enum, against := typ, other
+		if enum.enum == nil {
+			enum, against = other, typ
+		}
+		for _, v := range enum.enum {
+			if against.match(v) {
+				return false
+			}
+		}
+		return true
+	}
+	if typ.ranged && other.ranged {
+		return typ.max < other.min || other.max < typ.min
+	}
+	chars, required, ok := patternChars(typ.pattern)
+	otherChars, otherRequired, otherOK := patternChars(other.pattern)
+	if !ok || !otherOK {
+		return false
+	}
+	if !chars.intersects(otherChars) {
+		return true
+	}
+	for _, set := range required {
+		if !set.intersects(otherChars) {
+			return true
+		}
+	}
+	for _, set := range otherRequired {
+		if !set.intersects(chars) {
+			return true
+		}
+	}
+	return false
+}
+
+// charset is a set of ascii chars, other is set when it has any non ascii char
+type charset struct {
+	ascii [128]bool
+	other bool
+}
+
+func (s *charset) add(lo, hi rune) {
+	for r := lo; r <= hi && r < 128; r++ {
+		s.ascii[r] = true
+	}
+	if hi >= 128 {
+		s.other = true
+	}
+}
+
+func (s *charset) union(o charset) {
+	for i, ok := range o.ascii {
+		s.ascii[i] = s.ascii[i] || ok
+	}
+	s.other = s.other || o.other
+}
+
+func (s charset) intersects(o charset) bool {
+	if s.other && o.other {
+		return true
+	}
+	for i, ok := range s.ascii {
+		if ok && o.ascii[i] {
+			return true
+		}
+	}
+	return false
+}
+
+// patternChars returns the chars the matches of pattern may have,
+// and the sets every match has a char of
+func patternChars(pattern string) (chars charset, required []charset, ok bool) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return chars, nil, false
+	}
+	chars, required = regexpChars(re.Simplify())
+	return chars, required, true
+}
+
+func regexpChars(re *syntax.Regexp) (chars charset, required []charset) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			var set charset
+			set.add(r, r)
+			if re.Flags&syntax.FoldCase != 0 {
+				for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+					set.add(f, f)
+				}
+			}
+			chars.union(set)
+			required = append(required, set)
+		}
+	case syntax.OpCharClass:
+		for i := 0; i+1 < len(re.Rune); i += 2 {
+			chars.add(re.Rune[i], re.Rune[i+1])
+		}
+		required = append(required, chars)
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		chars.add(0, unicode.MaxRune)
+		required = append(required, chars)
+	case syntax.OpCapture, syntax.OpPlus:
+		return regexpChars(re.Sub[0])
+	case syntax.OpStar, syntax.OpQuest:
+		chars, _ = regexpChars(re.Sub[0])
+	case syntax.OpRepeat:
+		chars, required = regexpChars(re.Sub[0])
+		if re.Min < 1 {
+			required = nil
+		}
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			c, r := regexpChars(sub)
+			chars.union(c)
+			required = append(required, r...)
+		}
+	case syntax.OpAlternate:
+		// every match has a char of one of the alternates
+		all := true
+		for _, sub := range re.Sub {
+			c, r := regexpChars(sub)
+			chars.union(c)
+			all = all && len(r) > 0
+		}
+		if all {
+			required = append(required, chars)
+		}
+	}
+	return chars, required
+}
+
+func parseParamInt(s string) (interface{}, error) {
+	return strconv.Atoi(s)
+}
+
+func parseParamDate(s string) (interface{}, error) {
+	return time.Parse(paramDateLayout, s)
+}
+
+// isWordString reports whether s matches \w+
+func isWordString(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// isDigitString reports whether s matches \d+
+func isDigitString(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// isAlphaString reports whether s matches [A-Za-z]+
+func isAlphaString(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z') {
+			return false
+		}
+	}
+	return true
+}
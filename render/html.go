@@ -70,7 +70,7 @@ func (m HTMLRender) NewHTMLRender(dir string, funcMap template.FuncMap, delims D
 	}
 
 	// add view path
-	addViewPath(render.ViewPath)
+	addViewPath(render.ViewPath, render.FuncMap)
 	return render
 }
 
@@ -108,18 +108,18 @@ func (m HTMLRender) renderTemplate() (bytes.Buffer, error) {
 
 	if m.RunMode == defaultRunMode {
 		files := []string{m.Name}
-		BuildTemplate(m.ViewPath, files...)
+		buildTemplate(m.ViewPath, m.FuncMap, files...)
 	}
 	return buf, ExecuteTemplate(&buf, m.Name, m.ViewPath, m.RunMode, m.Data)
 }
 
 // addViewPath addViewPath
-func addViewPath(viewPath string) error {
+func addViewPath(viewPath string, funcs template.FuncMap) error {
 	if _, exist := beeViewPathTemplates[viewPath]; exist {
 		return nil
 	}
 	beeViewPathTemplates[viewPath] = make(map[string]*template.Template)
-	err := BuildTemplate(viewPath)
+	err := buildTemplate(viewPath, funcs)
 	return err
 }
 
@@ -215,6 +215,11 @@ func AddTemplateExt(ext string) {
 // BuildTemplate will build all template files in a directory.
 // it makes beego can render any template file in view directory.
 func BuildTemplate(dir string, files ...string) error {
+	return buildTemplate(dir, nil, files...)
+}
+
+// buildTemplate build the template files with funcs, funcs override the global template funcs
+func buildTemplate(dir string, funcs template.FuncMap, files ...string) error {
 	var err error
 	fs := beeTemplateFS()
 	f, err := fs.Open(dir)
@@ -250,11 +255,11 @@ func BuildTemplate(dir string, files ...string) error {
 				ext := filepath.Ext(file)
 				var t *template.Template
 				if len(ext) == 0 {
-					t, err = getTemplate(self.root, fs, file, v...)
+					t, err = getTemplate(self.root, fs, funcs, file, v...)
 				} else if fn, ok := beeTemplateEngines[ext[1:]]; ok {
-					t, err = fn(self.root, file, templateFuncMap)
+					t, err = fn(self.root, file, mergeFuncMap(funcs))
 				} else {
-					t, err = getTemplate(self.root, fs, file, v...)
+					t, err = getTemplate(self.root, fs, funcs, file, v...)
 				}
 				if err != nil {
 					log.Printf("parse template err: %v %v \n", file, err)
@@ -313,8 +318,23 @@ func getTplDeep(root string, fs http.FileSystem, file string, parent string, t *
 	return t, allSub, nil
 }
 
-func getTemplate(root string, fs http.FileSystem, file string, others ...string) (t *template.Template, err error) {
-	t = template.New(file).Delims(defaultDelims.Left, defaultDelims.Right).Funcs(templateFuncMap)
+// mergeFuncMap return the global template funcs overridden by funcs
+func mergeFuncMap(funcs template.FuncMap) template.FuncMap {
+	if len(funcs) == 0 {
+		return templateFuncMap
+	}
+	merged := make(template.FuncMap, len(templateFuncMap)+len(funcs))
+	for key, fn := range templateFuncMap {
+		merged[key] = fn
+	}
+	for key, fn := range funcs {
+		merged[key] = fn
+	}
+	return merged
+}
+
+func getTemplate(root string, fs http.FileSystem, funcs template.FuncMap, file string, others ...string) (t *template.Template, err error) {
+	t = template.New(file).Delims(defaultDelims.Left, defaultDelims.Right).Funcs(mergeFuncMap(funcs))
 	var subMods [][]string
 	t, subMods, err = getTplDeep(root, fs, file, "", t)
 	if err != nil {
@@ -44,6 +44,9 @@ type responseWriter struct {
 	http.ResponseWriter
 	size   int
 	status int
+
+	// noBody discards the body, it is set for HEAD requests served by GET routes
+	noBody bool
 }
 
 var _ ResponseWriter = &responseWriter{}
@@ -52,6 +55,7 @@ func (w *responseWriter) reset(writer http.ResponseWriter) {
 	w.ResponseWriter = writer
 	w.size = noWritten
 	w.status = defaultStatus
+	w.noBody = false
 }
 
 func (w *responseWriter) WriteHeader(code int) {
@@ -73,6 +77,10 @@ func (w *responseWriter) WriteHeaderNow() {
 
 func (w *responseWriter) Write(data []byte) (n int, err error) {
 	w.WriteHeaderNow()
+	if w.noBody {
+		w.size += len(data)
+		return len(data), nil
+	}
 	n, err = w.ResponseWriter.Write(data)
 	w.size += n
 	return
@@ -80,6 +88,10 @@ func (w *responseWriter) Write(data []byte) (n int, err error) {
 
 func (w *responseWriter) WriteString(s string) (n int, err error) {
 	w.WriteHeaderNow()
+	if w.noBody {
+		w.size += len(s)
+		return len(s), nil
+	}
 	n, err = io.WriteString(w.ResponseWriter, s)
 	w.size += n
 	return
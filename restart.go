@@ -0,0 +1,195 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package gow
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gkzy/gow/lib/logy"
+)
+
+const (
+	// envListeners the listeners passed to the new process by Restart, like tcp@:8080,unix@/tmp/app.sock,
+	// their fds start from 3 in order
+	envListeners = "GOW_LISTENERS"
+	// envReadyFd the fd of the pipe the new process closes when it serves all the listeners
+	envReadyFd = "GOW_READY_FD"
+
+	// restartReadyTimeout the time the new process has to start serving
+	restartReadyTimeout = time.Minute
+)
+
+// defaultRestartSignals the signals of a hot restart when HotRestart is on
+var defaultRestartSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+
+// inherited the listeners passed by the parent process, they are taken by the Run methods of the same address
+var inherited struct {
+	once      sync.Once
+	mu        sync.Mutex
+	listeners map[string]net.Listener
+	ready     *os.File
+}
+
+// takeInherited return the listener of the network and address inherited from the parent process or nil
+func takeInherited(network, address string) net.Listener {
+	inherited.once.Do(loadInherited)
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+	key := network + "@" + address
+	listener := inherited.listeners[key]
+	delete(inherited.listeners, key)
+	return listener
+}
+
+func loadInherited() {
+	specs := os.Getenv(envListeners)
+	readyFd, _ := strconv.Atoi(os.Getenv(envReadyFd))
+	os.Unsetenv(envListeners)
+	os.Unsetenv(envReadyFd)
+	if specs == "" {
+		return
+	}
+	inherited.listeners = make(map[string]net.Listener)
+	for i, spec := range strings.Split(specs, ",") {
+		f := os.NewFile(uintptr(3+i), spec)
+		listener, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			logy.Errorf("inherit listener %s: %v", spec, err)
+			continue
+		}
+		inherited.listeners[spec] = listener
+	}
+	if readyFd > 0 {
+		inherited.ready = os.NewFile(uintptr(readyFd), "ready")
+	}
+}
+
+// notifyReady tell the parent process to shut down when all the inherited listeners are served
+func notifyReady() {
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+	if inherited.ready == nil || len(inherited.listeners) > 0 {
+		return
+	}
+	inherited.ready.Write([]byte{1})
+	inherited.ready.Close()
+	inherited.ready = nil
+}
+
+// Restart start a new process of the executable with the same arguments and pass the listeners
+// of Run, RunTLS and RunUnix to it, the engine is shut down gracefully after the new process serves all of them
+//	it is called on SIGHUP and SIGUSR2 when HotRestart is on, the new process must run the same addresses,
+//	the restart is abandoned and the new process is killed when it does not serve them in one minute
+func (engine *Engine) Restart() error {
+	lc := engine.lifecycle
+	lc.mu.Lock()
+	if lc.closing || lc.restarting {
+		lc.mu.Unlock()
+		return errors.New("gow: the engine is shutting down or restarting")
+	}
+	lc.restarting = true
+	listeners := append([]trackedListener(nil), lc.listeners...)
+	lc.mu.Unlock()
+
+	err := engine.startProcess(listeners)
+	lc.mu.Lock()
+	lc.restarting = err == nil
+	lc.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		for _, l := range listeners {
+			if unix, ok := l.listener.(*net.UnixListener); ok {
+				unix.SetUnlinkOnClose(false)
+			}
+		}
+		engine.shutdownWithTimeout()
+	}()
+	return nil
+}
+
+// startProcess start the new process and wait for it to serve
+func (engine *Engine) startProcess(listeners []trackedListener) error {
+	path, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// the fds are passed by ForkExec, os/exec would call File.Fd, which puts the sockets
+	// shared with this process into blocking mode, and a blocking Accept can not be interrupted by Close
+	fds := []uintptr{os.Stdin.Fd(), os.Stdout.Fd(), os.Stderr.Fd()}
+	specs := make([]string, 0, len(listeners))
+	for _, l := range listeners {
+		conn, ok := l.listener.(syscall.Conn)
+		if !ok {
+			return fmt.Errorf("gow: can not pass the listener %s@%s", l.network, l.address)
+		}
+		raw, err := conn.SyscallConn()
+		if err != nil {
+			return err
+		}
+		if err = raw.Control(func(fd uintptr) {
+			fds = append(fds, fd)
+		}); err != nil {
+			return err
+		}
+		specs = append(specs, l.network+"@"+l.address)
+	}
+	ready, readyW, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	defer ready.Close()
+	fds = append(fds, readyW.Fd())
+
+	env := make([]string, 0, len(os.Environ())+2)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, envListeners+"=") && !strings.HasPrefix(kv, envReadyFd+"=") {
+			env = append(env, kv)
+		}
+	}
+	env = append(env, envListeners+"="+strings.Join(specs, ","), envReadyFd+"="+strconv.Itoa(3+len(specs)))
+
+	pid, err := syscall.ForkExec(path, append([]string{path}, os.Args[1:]...), &syscall.ProcAttr{Env: env, Files: fds})
+	readyW.Close()
+	if err != nil {
+		return err
+	}
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return err
+	}
+
+	// the read returns when the child writes the byte, or with EOF when it exits
+	readyC := make(chan error, 1)
+	go func() {
+		b := make([]byte, 1)
+		_, err := ready.Read(b)
+		readyC <- err
+	}()
+	select {
+	case err = <-readyC:
+		if err == nil {
+			logy.Infof("[%s] restarted as pid %d\n", engine.AppName, pid)
+			go process.Wait()
+			return nil
+		}
+		err = fmt.Errorf("gow: the new process exited before serving: %v", err)
+	case <-time.After(restartReadyTimeout):
+		err = errors.New("gow: the new process does not serve in time")
+	}
+	process.Kill()
+	process.Wait()
+	return err
+}
@@ -0,0 +1,163 @@
+//go:build !windows && !plan9
+// +build !windows,!plan9
+
+package gow
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestRestartProcess is the server process of TestRestart, it serves its pid on tcp and unix
+func TestRestartProcess(t *testing.T) {
+	addr, sock := os.Getenv("GOW_TEST_RESTART_ADDR"), os.Getenv("GOW_TEST_RESTART_SOCK")
+	if addr == "" {
+		t.Skip("the server process of TestRestart")
+	}
+	r := New()
+	r.HotRestart = true
+	r.GET("/pid", func(c *Context) {
+		c.String(strconv.Itoa(os.Getpid()))
+	})
+	go r.RunUnix(sock)
+	if err := r.Run(addr); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRestart(t *testing.T) {
+	if testing.Short() {
+		t.Skip("starts processes")
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := listener.Addr().String()
+	listener.Close()
+	sock := filepath.Join(t.TempDir(), "gow.sock")
+
+	cmd := exec.Command(os.Args[0], "-test.run=^TestRestartProcess$")
+	cmd.Env = append(os.Environ(), "GOW_TEST_RESTART_ADDR="+addr, "GOW_TEST_RESTART_SOCK="+sock)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer cmd.Process.Kill()
+
+	tcpClient := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}, Timeout: 5 * time.Second}
+	unixClient := &http.Client{Transport: &http.Transport{
+		DisableKeepAlives: true,
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", sock)
+		},
+	}, Timeout: 5 * time.Second}
+	getPid := func(client *http.Client, url string) (int, error) {
+		resp, err := client.Get(url)
+		if err != nil {
+			return 0, err
+		}
+		defer resp.Body.Close()
+		b, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			return 0, err
+		}
+		return strconv.Atoi(string(b))
+	}
+
+	var pid int
+	for i := 0; i < 100; i++ {
+		if pid, err = getPid(tcpClient, "http://"+addr+"/pid"); err == nil {
+			if _, err = getPid(unixClient, "http://unix/pid"); err == nil {
+				break
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("the server does not start: %v", err)
+	}
+	if pid != cmd.Process.Pid {
+		t.Fatalf("pid = %d, want %d", pid, cmd.Process.Pid)
+	}
+
+	exited := make(chan error, 1)
+	go func() {
+		exited <- cmd.Wait()
+	}()
+	cmd.Process.Signal(syscall.SIGHUP)
+
+	// the requests are served by either process during the restart, none of them fails
+	newPid := pid
+	deadline := time.Now().Add(10 * time.Second)
+	for newPid == pid && time.Now().Before(deadline) {
+		if newPid, err = getPid(tcpClient, "http://"+addr+"/pid"); err != nil {
+			t.Fatalf("request during restart: %v", err)
+		}
+	}
+	if newPid == pid {
+		t.Fatal("the new process does not serve")
+	}
+	defer syscall.Kill(newPid, syscall.SIGTERM)
+
+	select {
+	case err := <-exited:
+		if err != nil {
+			t.Errorf("the old process exits with %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("the old process does not exit")
+	}
+	if got, err := getPid(unixClient, "http://unix/pid"); err != nil || got != newPid {
+		t.Errorf("unix pid = %d, %v, want %d", got, err, newPid)
+	}
+	if got, err := getPid(tcpClient, "http://"+addr+"/pid"); err != nil || got != newPid {
+		t.Errorf("tcp pid = %d, %v, want %d", got, err, newPid)
+	}
+}
+
+func TestEngineShutdownOnSignal(t *testing.T) {
+	r := New()
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		time.Sleep(100 * time.Millisecond)
+		c.String("done")
+	})
+
+	url, done := runTestEngine(t, r)
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url + "/slow")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		body <- string(b)
+	}()
+	<-started
+
+	// the default ShutdownSignals catch SIGTERM, the in-flight request is drained
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	if got := <-body; got != "done" {
+		t.Errorf("in-flight response = %q, want done", got)
+	}
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("RunListener = %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunListener did not return after SIGTERM")
+	}
+}
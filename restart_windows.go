@@ -0,0 +1,24 @@
+//go:build windows || plan9
+// +build windows plan9
+
+package gow
+
+import (
+	"errors"
+	"net"
+	"os"
+)
+
+// defaultRestartSignals hot restart is not supported
+var defaultRestartSignals []os.Signal
+
+func takeInherited(network, address string) net.Listener {
+	return nil
+}
+
+func notifyReady() {}
+
+// Restart is not supported on windows and plan9
+func (engine *Engine) Restart() error {
+	return errors.New("gow: hot restart is not supported on this platform")
+}
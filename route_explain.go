@@ -0,0 +1,112 @@
+/*
+route diagnostics
+	fmt.Println(r.ExplainRoute("GET", "/user/sam"))
+	GET /user/sam -> /user/{name}
+		'user' matches static 'user'
+		'sam' does not match param '{uid:int}'
+		'sam' matches param '{name}'
+*/
+
+package gow
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// RouteExplanation reports how a request path is matched by ExplainRoute
+type RouteExplanation struct {
+	Method string `json:"method"`
+	Path   string `json:"path"`
+	// Route is the matched route path, or "" when nothing matches
+	Route string `json:"route"`
+	// Host and Listener are the host group of the matched route
+	Host     string   `json:"host,omitempty"`
+	Listener string   `json:"listener,omitempty"`
+	Params   Params   `json:"params"`
+	Steps    []string `json:"steps"`
+}
+
+// String formats the explanation as the matched route followed by the steps
+func (e *RouteExplanation) String() string {
+	var buf strings.Builder
+	route := e.Route
+	if route == "" {
+		route = "no route"
+	}
+	buf.WriteString(e.Method + " " + e.Path + " -> " + route)
+	for _, step := range e.Steps {
+		buf.WriteString("\n\t" + step)
+	}
+	return buf.String()
+}
+
+// ExplainRoute reports which route matches method and target, and why.
+// target is a path, or an absolute url whose host selects the host groups.
+// It is a debug api, the request is not served.
+//	r.ExplainRoute("GET", "http://sam.example.com/user/1")
+func (engine *Engine) ExplainRoute(method, target string) *RouteExplanation {
+	req := &http.Request{Method: method, URL: &url.URL{Path: target}}
+	if !strings.HasPrefix(target, "/") {
+		if u, err := url.Parse(target); err == nil {
+			req.URL, req.Host = u, u.Host
+		}
+	}
+	return engine.ExplainRequest(req)
+}
+
+// ExplainRequest is ExplainRoute of the request,
+// the host groups of its host and of the listener of Serve receiving it are tried first
+func (engine *Engine) ExplainRequest(req *http.Request) *RouteExplanation {
+	e := &RouteExplanation{Method: req.Method, Path: req.URL.Path}
+	host := requestHost(req)
+	listener, _ := req.Context().Value(listenerNameKey{}).(string)
+
+	params := make(Params, 0, engine.maxParams)
+	for _, h := range engine.hosts {
+		if !h.serves(host, listener, &muxMatch{params: &params}) {
+			continue
+		}
+		e.Steps = append(e.Steps, "trying the routes of "+h.String())
+		if engine.explainTrees(e, h.trees, &params) {
+			e.Host, e.Listener = h.pattern, h.listener
+			return e
+		}
+		params = params[:0]
+	}
+	if len(engine.hosts) > 0 {
+		e.Steps = append(e.Steps, "trying the default routes")
+	}
+	if engine.explainTrees(e, engine.trees, &params) {
+		return e
+	}
+	if allow := engine.allowed(host, listener, e.Path, false); allow != "" {
+		e.Steps = append(e.Steps, fmt.Sprintf("the path is registered for %s, the request is answered by 405 when HandleMethodNotAllowed is set", allow))
+	}
+	return e
+}
+
+// explainTrees traces the matching of the method tree of trees, it reports whether a route matches
+func (engine *Engine) explainTrees(e *RouteExplanation, trees methodTrees, params *Params) bool {
+	root := trees.get(e.Method)
+	if root == nil {
+		e.Steps = append(e.Steps, "no route is registered for method "+e.Method)
+		return false
+	}
+	m := &muxMatch{params: params, caseSensitive: engine.CaseSensitiveRouting, trace: &e.Steps}
+	n := root.root.find(e.Path, m)
+	if n == nil {
+		return false
+	}
+	e.Route = n.fullPath
+	e.Params = *params
+	e.Steps = append(e.Steps, "matched route "+n.fullPath)
+	for _, r := range root.routes {
+		if root.overlap(r, n) == n {
+			e.Steps = append(e.Steps, "route "+r.fullPath+" overlaps it and loses the paths matching both")
+		}
+	}
+	return true
+}
@@ -0,0 +1,66 @@
+/*
+route metadata
+	r.GET("/user/{uid:int}", handler).Meta(gow.RouteMeta{
+		Description: "user detail",
+		Tags:        []string{"user"},
+		Auth:        "token",
+	})
+read by handlers and middleware
+	if c.RouteMeta().Deprecated {
+		c.Header("Deprecation", "true")
+	}
+dump the route table as json
+	r.GET("/debug/routes", gow.DebugRoutes)
+*/
+
+package gow
+
+// RouteMeta is the metadata of a route
+type RouteMeta struct {
+	// Description describes the route
+	Description string `json:"description,omitempty"`
+
+	// Tags groups the routes, like user, order
+	Tags []string `json:"tags,omitempty"`
+
+	// Auth is the auth requirement, like token, or "" for a public route
+	Auth string `json:"auth,omitempty"`
+
+	// Deprecated marks a route to be removed
+	Deprecated bool `json:"deprecated,omitempty"`
+
+	// RateLimit is the rate limit of the route, like 100/m
+	RateLimit string `json:"rate_limit,omitempty"`
+
+	// Extra holds any other metadata
+	Extra map[string]interface{} `json:"extra,omitempty"`
+
+	// Request is a value of the json request body type, used by the openapi document
+	Request interface{} `json:"-"`
+
+	// Response is a value of the response data type, used by the openapi document.
+	// It is wrapped in DataResponse unless RawResponse is true.
+	Response interface{} `json:"-"`
+
+	// RawResponse marks a response written by c.JSON instead of c.DataJSON
+	RawResponse bool `json:"raw_response,omitempty"`
+
+	// Hidden hides the route from the openapi document
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// RouteMeta returns the metadata of the matched route,
+// it is empty when the route has no metadata
+func (c *Context) RouteMeta() RouteMeta {
+	if c.meta == nil {
+		return RouteMeta{}
+	}
+	return *c.meta
+}
+
+// DebugRoutes writes the route table as json,
+// including the middleware chain and the metadata of every route
+//	r.GET("/debug/routes", gow.DebugRoutes)
+func DebugRoutes(c *Context) {
+	c.JSON(c.engine.Routes())
+}
@@ -0,0 +1,69 @@
+package gow
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRouteMeta(t *testing.T) {
+	r := New()
+	var meta RouteMeta
+	h := func(c *Context) {
+		meta = c.RouteMeta()
+	}
+	auth := func(c *Context) {
+		c.Next()
+	}
+	api := r.Group("/api", auth)
+	api.GET("/user/{uid:int}", h).Meta(RouteMeta{
+		Description: "user detail",
+		Tags:        []string{"user"},
+		Deprecated:  true,
+	})
+	api.Any("/ping", h).Meta(RouteMeta{Auth: "token"})
+	r.GET("/plain", h)
+	r.GET("/debug/routes", DebugRoutes)
+
+	tests := []struct {
+		method string
+		path   string
+		want   RouteMeta
+	}{
+		{"GET", "/api/user/1", RouteMeta{Description: "user detail", Tags: []string{"user"}, Deprecated: true}},
+		{"GET", "/api/ping", RouteMeta{Auth: "token"}},
+		{"POST", "/api/ping", RouteMeta{Auth: "token"}},
+		{"GET", "/plain", RouteMeta{}},
+	}
+	for _, tt := range tests {
+		meta = RouteMeta{Description: "stale"}
+		r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(tt.method, tt.path, nil))
+		if meta.Description != tt.want.Description || meta.Auth != tt.want.Auth ||
+			meta.Deprecated != tt.want.Deprecated || len(meta.Tags) != len(tt.want.Tags) {
+			t.Errorf("%s %s meta = %+v, want %+v", tt.method, tt.path, meta, tt.want)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/debug/routes", nil))
+	var routes []RouteInfo
+	if err := json.Unmarshal(w.Body.Bytes(), &routes); err != nil {
+		t.Fatalf("decode /debug/routes: %v, body %s", err, w.Body.String())
+	}
+	var found bool
+	for _, route := range routes {
+		if route.Path != "/api/user/{uid:int}" {
+			continue
+		}
+		found = true
+		if route.Meta == nil || route.Meta.Description != "user detail" {
+			t.Errorf("route meta = %+v", route.Meta)
+		}
+		if len(route.Middleware) != 1 {
+			t.Errorf("route middleware = %v, want 1 entry", route.Middleware)
+		}
+	}
+	if !found {
+		t.Errorf("/api/user/{uid:int} not in %s", w.Body.String())
+	}
+}
@@ -19,6 +19,8 @@ type IRouter interface {
 // IRoutes defines all router handle interface.
 type IRoutes interface {
 	Use(...HandlerFunc) IRoutes
+	Name(string) IRoutes
+	Meta(RouteMeta) IRoutes
 
 	Handle(string, string, ...HandlerFunc) IRoutes
 	Any(string, ...HandlerFunc) IRoutes
@@ -33,6 +35,16 @@ type IRoutes interface {
 	StaticFile(string, string) IRoutes
 	Static(string, string) IRoutes
 	StaticFS(string, http.FileSystem) IRoutes
+
+	Mount(string, http.Handler) IRoutes
+	MountIsolated(string, http.Handler) IRoutes
+}
+
+// anyMethods are the methods registered by Any
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut,
+	http.MethodPatch, http.MethodHead, http.MethodOptions,
+	http.MethodDelete, http.MethodConnect, http.MethodTrace,
 }
 
 // RouterGroup is used internally to configure router, a RouterGroup is associated with
@@ -42,6 +54,12 @@ type RouterGroup struct {
 	basePath string
 	engine   *Engine
 	root     bool
+
+	// lastRoutes are the routes registered by the last call, like GET or Any
+	lastRoutes []*muxNode
+
+	// host is the host pattern or the listener of the group, nil for the default routes
+	host *hostRouter
 }
 
 var _ IRouter = &RouterGroup{}
@@ -52,6 +70,26 @@ func (group *RouterGroup) Use(middleware ...HandlerFunc) IRoutes {
 	return group.returnObj()
 }
 
+// Name names the route registered last by the group,
+// so its url can be built by Engine.URLFor
+//	r.GET("/user/{uid:int}", handler).Name("user.show")
+func (group *RouterGroup) Name(name string) IRoutes {
+	assert1(len(group.lastRoutes) > 0, "there is no route to name '"+name+"'")
+	group.engine.addRouteName(name, group.lastRoutes[len(group.lastRoutes)-1])
+	return group.returnObj()
+}
+
+// Meta attaches metadata to the routes registered last by the group,
+// handlers read it by Context.RouteMeta
+//	r.GET("/user/{uid:int}", handler).Meta(gow.RouteMeta{Description: "user detail", Tags: []string{"user"}})
+func (group *RouterGroup) Meta(meta RouteMeta) IRoutes {
+	assert1(len(group.lastRoutes) > 0, "there is no route to attach meta")
+	for _, n := range group.lastRoutes {
+		n.meta = &meta
+	}
+	return group.returnObj()
+}
+
 // Group creates a new router group. You should add all the routes that have common middlewares or the same path prefix.
 // For example, all the routes that use a common middleware for authorization could be grouped.
 func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *RouterGroup {
@@ -59,6 +97,7 @@ func (group *RouterGroup) Group(relativePath string, handlers ...HandlerFunc) *R
 		Handlers: group.combineHandlers(handlers),
 		basePath: group.calculateAbsolutePath(relativePath),
 		engine:   group.engine,
+		host:     group.host,
 	}
 }
 
@@ -71,7 +110,8 @@ func (group *RouterGroup) BasePath() string {
 func (group *RouterGroup) handle(httpMethod, relativePath string, handlers HandlersChain) IRoutes {
 	absolutePath := group.calculateAbsolutePath(relativePath)
 	handlers = group.combineHandlers(handlers)
-	group.engine.addRoute(httpMethod, absolutePath, handlers)
+	n := group.engine.addRoute(group.host, httpMethod, absolutePath, handlers)
+	group.lastRoutes = []*muxNode{n}
 	return group.returnObj()
 }
 
@@ -130,15 +170,12 @@ func (group *RouterGroup) HEAD(relativePath string, handlers ...HandlerFunc) IRo
 // Any registers a route that matches all the HTTP methods.
 // GET, POST, PUT, PATCH, HEAD, OPTIONS, DELETE, CONNECT, TRACE.
 func (group *RouterGroup) Any(relativePath string, handlers ...HandlerFunc) IRoutes {
-	group.handle(http.MethodGet, relativePath, handlers)
-	group.handle(http.MethodPost, relativePath, handlers)
-	group.handle(http.MethodPut, relativePath, handlers)
-	group.handle(http.MethodPatch, relativePath, handlers)
-	group.handle(http.MethodHead, relativePath, handlers)
-	group.handle(http.MethodOptions, relativePath, handlers)
-	group.handle(http.MethodDelete, relativePath, handlers)
-	group.handle(http.MethodConnect, relativePath, handlers)
-	group.handle(http.MethodTrace, relativePath, handlers)
+	routes := make([]*muxNode, 0, len(anyMethods))
+	for _, method := range anyMethods {
+		group.handle(method, relativePath, handlers)
+		routes = append(routes, group.lastRoutes...)
+	}
+	group.lastRoutes = routes
 	return group.returnObj()
 }
 
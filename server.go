@@ -0,0 +1,393 @@
+package gow
+
+import (
+	"context"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gkzy/gow/lib/logy"
+)
+
+const (
+	// defaultShutdownTimeout the time to drain the in-flight requests after a shutdown signal
+	defaultShutdownTimeout = 10 * time.Second
+	// defaultReadHeaderTimeout a client must send the request headers in time, against slowloris
+	defaultReadHeaderTimeout = 10 * time.Second
+	// defaultIdleTimeout the keep-alive connections are closed after idle so long
+	defaultIdleTimeout = 120 * time.Second
+)
+
+// DefaultShutdownSignals the signals of Ctrl+C and k8s, the default ShutdownSignals of the engine,
+// set ShutdownSignals to nil when the app handles the signals itself
+//	r.ShutdownSignals = nil
+var DefaultShutdownSignals = []os.Signal{os.Interrupt, syscall.SIGTERM}
+
+// lifecycle the servers started by the Run methods of an engine and the hooks around them
+type lifecycle struct {
+	mu         sync.Mutex
+	servers    map[*http.Server]*managedServer
+	listeners  []trackedListener
+	newConns   map[net.Conn]struct{}
+	h2cConns   int32
+	closing    bool
+	restarting bool
+	done       chan struct{}
+
+	onStart    []func() error
+	onShutdown []func(ctx context.Context) error
+
+	startOnce    sync.Once
+	startErr     error
+	signalOnce   sync.Once
+	shutdownOnce sync.Once
+	shutdownErr  error
+}
+
+// managedServer a server and its listener, served is closed when the server stops accepting
+type managedServer struct {
+	listener net.Listener
+	served   chan struct{}
+}
+
+// trackedListener a listener of Run, RunTLS or RunUnix, it is passed to the new process by Restart
+type trackedListener struct {
+	network  string
+	address  string
+	listener net.Listener
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{
+		servers:  make(map[*http.Server]*managedServer),
+		newConns: make(map[net.Conn]struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+// OnStart add hooks called once before the engine starts serving, the listener is bound already,
+// Run returns the error of a hook without serving
+//	r.OnStart(func() error {
+//		return db.Ping()
+//	})
+func (engine *Engine) OnStart(hooks ...func() error) {
+	engine.lifecycle.mu.Lock()
+	engine.lifecycle.onStart = append(engine.lifecycle.onStart, hooks...)
+	engine.lifecycle.mu.Unlock()
+}
+
+// OnShutdown add hooks called in order after the in-flight requests are drained,
+// they are called even when the drain times out, the error of a hook does not stop the next ones
+//	r.OnShutdown(func(ctx context.Context) error {
+//		consumer.Stop()
+//		return nil
+//	}, func(ctx context.Context) error {
+//		pool.Close()
+//		return nil
+//	})
+func (engine *Engine) OnShutdown(hooks ...func(ctx context.Context) error) {
+	engine.lifecycle.mu.Lock()
+	engine.lifecycle.onShutdown = append(engine.lifecycle.onShutdown, hooks...)
+	engine.lifecycle.mu.Unlock()
+}
+
+// Shutdown stop the servers started by the Run methods gracefully and call the OnShutdown hooks
+//	the listeners are closed first, then it waits for the in-flight requests until ctx is done,
+//	the remaining connections are closed when ctx is done, hijacked connections like websockets are not tracked.
+//	Run returns nil after a graceful shutdown, Shutdown returns the first error of the drain and the hooks,
+//	it can be called more than once, the later calls wait for the first one
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	lc := engine.lifecycle
+	lc.shutdownOnce.Do(func() {
+		lc.mu.Lock()
+		lc.closing = true
+		servers := make(map[*http.Server]*managedServer, len(lc.servers))
+		for srv, ms := range lc.servers {
+			servers[srv] = ms
+		}
+		hooks := lc.onShutdown
+		lc.mu.Unlock()
+
+		// http.Server.Shutdown drops the connections accepted but not read yet,
+		// so stop accepting and let them send their requests before it
+		for _, ms := range servers {
+			ms.listener.Close()
+		}
+		for _, ms := range servers {
+			select {
+			case <-ms.served:
+			case <-ctx.Done():
+			}
+		}
+		lc.waitNewConns(ctx)
+
+		errs := make(chan error, len(servers))
+		for srv := range servers {
+			go func(srv *http.Server) {
+				err := srv.Shutdown(ctx)
+				if err != nil {
+					srv.Close()
+				}
+				errs <- err
+			}(srv)
+		}
+		for range servers {
+			if err := <-errs; err != nil && lc.shutdownErr == nil {
+				lc.shutdownErr = err
+			}
+		}
+		// the h2c connections are hijacked, they are closed after GOAWAY and their streams
+		if err := lc.waitH2CConns(ctx); err != nil && lc.shutdownErr == nil {
+			lc.shutdownErr = err
+		}
+
+		for _, hook := range hooks {
+			if err := hook(ctx); err != nil {
+				logy.Errorf("[%s] shutdown hook %s: %v", engine.AppName, nameOfFunction(hook), err)
+				if lc.shutdownErr == nil {
+					lc.shutdownErr = err
+				}
+			}
+		}
+		close(lc.done)
+	})
+	return lc.shutdownErr
+}
+
+// newConnWait a connection is treated as idle when it sends nothing in the time, like http.Server.Shutdown
+const newConnWait = 5 * time.Second
+
+// waitNewConns wait for the accepted connections to send their first requests
+func (lc *lifecycle) waitNewConns(ctx context.Context) {
+	timer := time.NewTimer(newConnWait)
+	defer timer.Stop()
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		lc.mu.Lock()
+		n := len(lc.newConns)
+		lc.mu.Unlock()
+		if n == 0 {
+			return
+		}
+		select {
+		case <-ticker.C:
+		case <-timer.C:
+			return
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// waitH2CConns wait for the h2c connections to be closed until ctx is done
+func (lc *lifecycle) waitH2CConns(ctx context.Context) error {
+	ticker := time.NewTicker(5 * time.Millisecond)
+	defer ticker.Stop()
+	for atomic.LoadInt32(&lc.h2cConns) > 0 {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// connState track the connections that have not sent a request
+func (lc *lifecycle) connState(conn net.Conn, state http.ConnState) {
+	lc.mu.Lock()
+	if state == http.StateNew {
+		lc.newConns[conn] = struct{}{}
+	} else {
+		delete(lc.newConns, conn)
+	}
+	lc.mu.Unlock()
+}
+
+// serve run a managed server on the listener until it fails or the engine is shut down,
+// serveFn is (*http.Server).Serve or a ServeTLS closure
+func (engine *Engine) serve(listener net.Listener, serveFn func(srv *http.Server, listener net.Listener) error) error {
+	lc := engine.lifecycle
+	srv := engine.newServer()
+	ms := &managedServer{listener: listener, served: make(chan struct{})}
+	lc.mu.Lock()
+	if lc.closing {
+		lc.mu.Unlock()
+		listener.Close()
+		return http.ErrServerClosed
+	}
+	lc.servers[srv] = ms
+	hooks := lc.onStart
+	lc.mu.Unlock()
+
+	lc.startOnce.Do(func() {
+		for _, hook := range hooks {
+			if lc.startErr = hook(); lc.startErr != nil {
+				return
+			}
+		}
+	})
+	if lc.startErr != nil {
+		close(ms.served)
+		engine.removeServer(srv, listener)
+		listener.Close()
+		return lc.startErr
+	}
+	engine.watchSignals()
+	notifyReady()
+
+	err := serveFn(srv, listener)
+	close(ms.served)
+	lc.mu.Lock()
+	closing := lc.closing
+	lc.mu.Unlock()
+	if closing {
+		// the listener is closed by Shutdown
+		<-lc.done
+		return nil
+	}
+	engine.removeServer(srv, listener)
+	return err
+}
+
+// listen return the listener inherited from the parent process of a hot restart, or listen on the address,
+// the listener is passed to the new process by Restart
+func (engine *Engine) listen(network, address string) (net.Listener, error) {
+	listener := takeInherited(network, address)
+	if listener == nil {
+		var err error
+		if listener, err = net.Listen(network, address); err != nil {
+			return nil, err
+		}
+	}
+	lc := engine.lifecycle
+	lc.mu.Lock()
+	lc.listeners = append(lc.listeners, trackedListener{network: network, address: address, listener: listener})
+	lc.mu.Unlock()
+	return listener, nil
+}
+
+// newServer return the http.Server of a listener with the timeouts and limits of the engine,
+// ConfigureServer is called at last
+func (engine *Engine) newServer() *http.Server {
+	srv := &http.Server{
+		Handler:           engine,
+		ReadTimeout:       engine.ReadTimeout,
+		ReadHeaderTimeout: engine.ReadHeaderTimeout,
+		WriteTimeout:      engine.WriteTimeout,
+		IdleTimeout:       engine.IdleTimeout,
+		MaxHeaderBytes:    engine.MaxHeaderBytes,
+		ErrorLog:          engine.ErrorLog,
+	}
+	if srv.ErrorLog == nil {
+		srv.ErrorLog = log.New(serverErrorWriter{engine}, "", 0)
+	}
+	if engine.ConfigureServer != nil {
+		engine.ConfigureServer(srv)
+	}
+	hook := srv.ConnState
+	srv.ConnState = func(conn net.Conn, state http.ConnState) {
+		engine.lifecycle.connState(conn, state)
+		if hook != nil {
+			hook(conn, state)
+		}
+	}
+	return srv
+}
+
+// serverErrorWriter write the errors of http.Server, like TLS handshake errors and panics, into logy
+type serverErrorWriter struct {
+	engine *Engine
+}
+
+func (w serverErrorWriter) Write(p []byte) (int, error) {
+	logy.Errorf("[%s] http: %s", w.engine.AppName, strings.TrimSpace(strings.TrimPrefix(string(p), "http: ")))
+	return len(p), nil
+}
+
+func (engine *Engine) removeServer(srv *http.Server, listener net.Listener) {
+	lc := engine.lifecycle
+	lc.mu.Lock()
+	delete(lc.servers, srv)
+	for i, l := range lc.listeners {
+		if l.listener == listener {
+			lc.listeners = append(lc.listeners[:i], lc.listeners[i+1:]...)
+			break
+		}
+	}
+	lc.mu.Unlock()
+}
+
+// restarted report whether the listeners are passed to a new process
+func (engine *Engine) restarted() bool {
+	engine.lifecycle.mu.Lock()
+	defer engine.lifecycle.mu.Unlock()
+	return engine.lifecycle.restarting
+}
+
+// watchSignals shut down the engine on the first of ShutdownSignals, with ShutdownTimeout to drain,
+// and restart it on SIGHUP and SIGUSR2 when HotRestart is on
+func (engine *Engine) watchSignals() {
+	shutdownSignals := engine.ShutdownSignals
+	var restartSignals []os.Signal
+	if engine.HotRestart {
+		restartSignals = defaultRestartSignals
+	}
+	if len(shutdownSignals)+len(restartSignals) == 0 {
+		return
+	}
+	lc := engine.lifecycle
+	lc.signalOnce.Do(func() {
+		ch := make(chan os.Signal, 1)
+		signal.Notify(ch, append(append([]os.Signal(nil), shutdownSignals...), restartSignals...)...)
+		go func() {
+			defer signal.Stop(ch)
+			for {
+				select {
+				case sig := <-ch:
+					if !containsSignal(restartSignals, sig) {
+						logy.Infof("[%s] received %s, shutting down\n", engine.AppName, sig)
+						engine.shutdownWithTimeout()
+						return
+					}
+					logy.Infof("[%s] received %s, restarting\n", engine.AppName, sig)
+					if err := engine.Restart(); err != nil {
+						logy.Errorf("[%s] restart: %v", engine.AppName, err)
+					}
+				case <-lc.done:
+					return
+				}
+			}
+		}()
+	})
+}
+
+// shutdownWithTimeout shut down the engine with ShutdownTimeout to drain
+func (engine *Engine) shutdownWithTimeout() {
+	ctx := context.Background()
+	if engine.ShutdownTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, engine.ShutdownTimeout)
+		defer cancel()
+	}
+	if err := engine.Shutdown(ctx); err != nil {
+		logy.Errorf("[%s] shutdown: %v", engine.AppName, err)
+	}
+}
+
+func containsSignal(signals []os.Signal, sig os.Signal) bool {
+	for _, s := range signals {
+		if s == sig {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,228 @@
+package gow
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gkzy/gow/lib/config"
+)
+
+// runTestEngine run the engine on a random port, it returns the url and the result of RunListener
+func runTestEngine(t *testing.T, r *Engine) (string, <-chan error) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- r.RunListener(listener)
+	}()
+	return "http://" + listener.Addr().String(), done
+}
+
+func TestEngineShutdownDrainsRequests(t *testing.T) {
+	r := New()
+	r.ShutdownSignals = nil
+	started := make(chan struct{})
+	release := make(chan struct{})
+	r.GET("/pay/notify", func(c *Context) {
+		close(started)
+		<-release
+		c.String("success")
+	})
+	var hooks []string
+	r.OnStart(func() error {
+		hooks = append(hooks, "start")
+		return nil
+	})
+	r.OnShutdown(func(ctx context.Context) error {
+		hooks = append(hooks, "nsq")
+		return nil
+	}, func(ctx context.Context) error {
+		hooks = append(hooks, "rpc")
+		return nil
+	})
+
+	url, done := runTestEngine(t, r)
+	body := make(chan string, 1)
+	go func() {
+		resp, err := http.Get(url + "/pay/notify")
+		if err != nil {
+			body <- err.Error()
+			return
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		body <- string(b)
+	}()
+	<-started
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- r.Shutdown(context.Background())
+	}()
+	select {
+	case err := <-shutdown:
+		t.Fatalf("Shutdown returned %v before the request finished", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if _, err := http.Get(url + "/pay/notify"); err == nil {
+		t.Error("new connections are accepted while shutting down")
+	}
+
+	close(release)
+	if got := <-body; got != "success" {
+		t.Errorf("in-flight response = %q, want success", got)
+	}
+	if err := <-shutdown; err != nil {
+		t.Errorf("Shutdown = %v", err)
+	}
+	if err := <-done; err != nil {
+		t.Errorf("RunListener = %v, want nil", err)
+	}
+	if got := strings.Join(hooks, ","); got != "start,nsq,rpc" {
+		t.Errorf("hooks = %s", got)
+	}
+	if err := r.Shutdown(context.Background()); err != nil {
+		t.Errorf("second Shutdown = %v", err)
+	}
+}
+
+func TestEngineShutdownTimeout(t *testing.T) {
+	r := New()
+	r.ShutdownSignals = nil
+	started := make(chan struct{})
+	r.GET("/slow", func(c *Context) {
+		close(started)
+		<-c.Request.Context().Done()
+	})
+	hookErr := errors.New("close pool")
+	var called bool
+	r.OnShutdown(func(ctx context.Context) error {
+		called = true
+		return hookErr
+	})
+
+	url, done := runTestEngine(t, r)
+	go http.Get(url + "/slow")
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := r.Shutdown(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Shutdown = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if !called {
+		t.Error("the shutdown hook is not called after the timeout")
+	}
+	<-done
+}
+
+func TestEngineStartHookError(t *testing.T) {
+	r := New()
+	r.ShutdownSignals = nil
+	startErr := errors.New("db is down")
+	r.OnStart(func() error {
+		return startErr
+	})
+	_, done := runTestEngine(t, r)
+	if err := <-done; err != startErr {
+		t.Errorf("RunListener = %v, want %v", err, startErr)
+	}
+
+	r.Shutdown(context.Background())
+	_, done = runTestEngine(t, r)
+	if err := <-done; err != http.ErrServerClosed {
+		t.Errorf("RunListener after Shutdown = %v, want %v", err, http.ErrServerClosed)
+	}
+}
+
+func TestEngineServerLimits(t *testing.T) {
+	r := New()
+	r.ShutdownSignals = nil
+	r.ReadHeaderTimeout = 100 * time.Millisecond
+	r.MaxHeaderBytes = 1 << 10
+	r.WriteTimeout = time.Minute
+	var srv *http.Server
+	r.ConfigureServer = func(s *http.Server) {
+		srv = s
+	}
+	r.GET("/", func(c *Context) {
+		c.String("ok")
+	})
+	url, done := runTestEngine(t, r)
+	defer func() {
+		r.Shutdown(context.Background())
+		<-done
+	}()
+
+	req, _ := http.NewRequest("GET", url, nil)
+	req.Header.Set("X-Large", strings.Repeat("a", 8<<10))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusRequestHeaderFieldsTooLarge {
+		t.Errorf("large header status = %d, want 431", resp.StatusCode)
+	}
+	if srv == nil || srv.WriteTimeout != time.Minute || srv.IdleTimeout != defaultIdleTimeout || srv.ErrorLog == nil {
+		t.Fatalf("ConfigureServer got %+v", srv)
+	}
+
+	// a slow client sending the headers is disconnected
+	conn, err := net.Dial("tcp", strings.TrimPrefix(url, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: a\r\n"))
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	start := time.Now()
+	if _, err := bufio.NewReader(conn).ReadString('\n'); err == nil {
+		t.Error("the connection is not closed")
+	} else if d := time.Since(start); d > time.Second {
+		t.Errorf("the connection is closed after %v", d)
+	}
+}
+
+func TestEngineSetAppConfig(t *testing.T) {
+	wd, _ := os.Getwd()
+	os.Chdir(t.TempDir())
+	os.Mkdir("conf", 0755)
+	defer func() {
+		config.WriteContent("")
+		config.Reload()
+		os.Chdir(wd)
+	}()
+	config.InitLoad("app.conf")
+	config.WriteContent("idle_timeout = 0\nread_header_timeout = 5\nwrite_timeout = 1 minute\n")
+	if err := config.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	r := New()
+	r.H2C = true
+	r.WriteTimeout = time.Minute
+	r.SetAppConfig(GetAppConfig())
+	// 0 disables the idle timeout, the invalid write_timeout and the missing h2c keep the engine values
+	if r.IdleTimeout != 0 || r.ReadHeaderTimeout != 5*time.Second || r.WriteTimeout != time.Minute ||
+		!r.H2C || r.ShutdownTimeout != defaultShutdownTimeout {
+		t.Errorf("idle = %v, read header = %v, write = %v, h2c = %v, shutdown = %v",
+			r.IdleTimeout, r.ReadHeaderTimeout, r.WriteTimeout, r.H2C, r.ShutdownTimeout)
+	}
+
+	// the zero values of an AppConfig built by hand keep the engine values
+	r.SetAppConfig(&AppConfig{ReadTimeout: time.Second})
+	if r.ReadTimeout != time.Second || r.ReadHeaderTimeout != 5*time.Second || !r.H2C {
+		t.Errorf("read = %v, read header = %v, h2c = %v", r.ReadTimeout, r.ReadHeaderTimeout, r.H2C)
+	}
+}
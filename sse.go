@@ -0,0 +1,182 @@
+package gow
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// SSEvent a server-sent event
+//	Data is written as is when it is a string or []byte, otherwise it is encoded as JSON
+type SSEvent struct {
+	ID    string
+	Event string
+	Retry time.Duration
+	Data  interface{}
+}
+
+// SSEConfig config of SSEStream
+type SSEConfig struct {
+	// Retry tell the client how long to wait before reconnecting
+	Retry time.Duration
+
+	// Heartbeat write a comment line at this interval to keep proxies from closing the idle connection
+	Heartbeat time.Duration
+
+	// Resume return the events missed by a reconnecting client,
+	// it is called with the Last-Event-ID sent by the client
+	Resume func(lastEventID string) []SSEvent
+}
+
+var sseReplacer = strings.NewReplacer("\n", "", "\r", "")
+
+// SSEvent write a server-sent event and flush it to the client
+//	c.SSEvent("message", gow.H{"uid": 1})
+func (c *Context) SSEvent(name string, data interface{}) {
+	c.WriteSSEvent(SSEvent{Event: name, Data: data})
+}
+
+// WriteSSEvent write a server-sent event with id and retry and flush it to the client
+func (c *Context) WriteSSEvent(event SSEvent) error {
+	c.sseHeader()
+	if err := writeSSEvent(c.Writer, event); err != nil {
+		return err
+	}
+	c.Writer.Flush()
+	return nil
+}
+
+// SSEHeartbeat write a comment line, the client ignores it
+func (c *Context) SSEHeartbeat() {
+	c.sseHeader()
+	c.Writer.WriteString(": heartbeat\n\n")
+	c.Writer.Flush()
+}
+
+// LastEventID return the id of the last event received by a reconnecting client
+func (c *Context) LastEventID() string {
+	if id := c.GetHeader("Last-Event-ID"); id != "" {
+		return id
+	}
+	return c.Query("lastEventId")
+}
+
+// SSEStream write the events from the channel until it is closed or the client disconnects
+//	returns true when the client disconnected
+//	r.GET("/events", func(c *gow.Context) {
+//		c.SSEStream(hub.Subscribe(), gow.SSEConfig{Heartbeat: 15 * time.Second})
+//	})
+func (c *Context) SSEStream(events <-chan SSEvent, config SSEConfig) bool {
+	c.sseHeader()
+	if config.Retry > 0 {
+		fmt.Fprintf(c.Writer, "retry: %d\n\n", config.Retry.Milliseconds())
+	}
+	if config.Resume != nil {
+		if id := c.LastEventID(); id != "" {
+			for _, event := range config.Resume(id) {
+				if err := writeSSEvent(c.Writer, event); err != nil {
+					debugPrint("[WARNING] write sse event error:%v", err)
+				}
+			}
+		}
+	}
+	c.Writer.Flush()
+
+	var heartbeat <-chan time.Time
+	if config.Heartbeat > 0 {
+		ticker := time.NewTicker(config.Heartbeat)
+		defer ticker.Stop()
+		heartbeat = ticker.C
+	}
+	done := c.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		case <-heartbeat:
+			c.SSEHeartbeat()
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if err := c.WriteSSEvent(event); err != nil {
+				debugPrint("[WARNING] write sse event error:%v", err)
+			}
+		}
+	}
+}
+
+// Stream call step and flush the response until step returns false or the client disconnects
+//	returns true when the client disconnected
+//	c.Stream(func(w io.Writer) bool {
+//		msg, ok := <-messages
+//		if ok {
+//			w.Write(msg)
+//		}
+//		return ok
+//	})
+func (c *Context) Stream(step func(w io.Writer) bool) bool {
+	done := c.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(c.Writer)
+			c.Writer.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// sseHeader set the event stream headers before the first write
+func (c *Context) sseHeader() {
+	if c.Writer.Written() {
+		return
+	}
+	header := c.Writer.Header()
+	header.Set("Content-Type", ContentEventStream)
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	header.Set("X-Accel-Buffering", "no")
+	header.Del("Content-Length")
+}
+
+// writeSSEvent write an event in the text/event-stream format
+func writeSSEvent(w io.Writer, event SSEvent) error {
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: " + sseReplacer.Replace(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + sseReplacer.Replace(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", event.Retry.Milliseconds())
+	}
+	var data string
+	switch v := event.Data.(type) {
+	case nil:
+	case string:
+		data = v
+	case []byte:
+		data = string(v)
+	default:
+		buf, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		data = string(buf)
+	}
+	data = strings.ReplaceAll(data, "\r\n", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
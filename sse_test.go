@@ -0,0 +1,99 @@
+package gow
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestContextSSEvent(t *testing.T) {
+	r := New()
+	r.GET("/events", func(c *Context) {
+		c.SSEvent("message", "hello\nworld")
+		c.WriteSSEvent(SSEvent{ID: "2", Event: "user", Retry: time.Second, Data: H{"uid": 1}})
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest("GET", "/events", nil))
+	want := "event: message\ndata: hello\ndata: world\n\n" +
+		"id: 2\nevent: user\nretry: 1000\ndata: {\"uid\":1}\n\n"
+	if w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+	if w.Header().Get("Content-Type") != ContentEventStream || w.Header().Get("Cache-Control") != "no-cache" || !w.Flushed {
+		t.Errorf("header = %v, flushed = %v", w.Header(), w.Flushed)
+	}
+}
+
+func TestContextSSEStream(t *testing.T) {
+	events := make(chan SSEvent, 2)
+	events <- SSEvent{ID: "3", Data: "c"}
+	close(events)
+
+	r := New()
+	r.GET("/events", func(c *Context) {
+		disconnected := c.SSEStream(events, SSEConfig{
+			Retry: 3 * time.Second,
+			Resume: func(lastEventID string) []SSEvent {
+				if lastEventID != "1" {
+					t.Errorf("last event id = %q", lastEventID)
+				}
+				return []SSEvent{{ID: "2", Data: "b"}}
+			},
+		})
+		if disconnected {
+			t.Error("disconnected = true, want false")
+		}
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	r.ServeHTTP(w, req)
+	want := "retry: 3000\n\nid: 2\ndata: b\n\nid: 3\ndata: c\n\n"
+	if w.Body.String() != want {
+		t.Errorf("body = %q, want %q", w.Body.String(), want)
+	}
+}
+
+func TestContextStream(t *testing.T) {
+	r := New()
+	r.Use(Gzip(DefaultCompression))
+	var disconnected bool
+	r.GET("/stream", func(c *Context) {
+		n := 0
+		disconnected = c.Stream(func(w io.Writer) bool {
+			n++
+			c.SSEvent("tick", n)
+			return n < 3
+		})
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/stream", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	r.ServeHTTP(w, req)
+	gz, err := gzip.NewReader(w.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(gz)
+	want := "event: tick\ndata: 1\n\nevent: tick\ndata: 2\n\nevent: tick\ndata: 3\n\n"
+	if string(body) != want || disconnected {
+		t.Errorf("body = %q, disconnected = %v", body, disconnected)
+	}
+	if w.Header().Get("Content-Encoding") != "gzip" || w.Header().Get("Content-Type") != ContentEventStream {
+		t.Errorf("header = %v", w.Header())
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/stream", nil).WithContext(ctx))
+	if !disconnected {
+		t.Error("disconnected = false after the client went away")
+	}
+}
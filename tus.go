@@ -0,0 +1,578 @@
+/*
+resumable uploads with the tus 1.0 protocol, https://tus.io/protocols/resumable-upload.html
+the creation, creation-with-upload, expiration and termination extensions are supported
+	tus := gow.NewTusHandler(gow.TusConfig{
+		Store:   &gow.TusFileStore{Dir: "./data/tus"},
+		MaxSize: 2 << 30,
+		Storage: oss.NewStorage(client, "video"),
+	})
+	r.Mount("/files", tus)
+	POST   /files       create an upload with Upload-Length and Upload-Metadata
+	HEAD   /files/{id}  the current Upload-Offset
+	PATCH  /files/{id}  append a chunk at Upload-Offset
+	DELETE /files/{id}  terminate the upload
+*/
+
+package gow
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// TusVersion the version of the tus protocol
+	TusVersion = "1.0.0"
+
+	tusExtensions     = "creation,creation-with-upload,expiration,termination"
+	tusContentType    = "application/offset+octet-stream"
+	defaultTusExpiry  = 24 * time.Hour
+	tusIDParam        = "id"
+	tusFileSuffix     = ".bin"
+	tusInfoSuffix     = ".info"
+	tusUploadIDLength = 32
+)
+
+var (
+	// ErrTusNotFound the upload does not exist
+	ErrTusNotFound = errors.New("tus: upload not found")
+	// ErrTusOffset the offset of the chunk is not the offset of the upload
+	ErrTusOffset = errors.New("tus: upload offset mismatch")
+)
+
+// TusUpload the state of a resumable upload
+type TusUpload struct {
+	ID        string            `json:"id"`
+	Size      int64             `json:"size"`
+	Offset    int64             `json:"offset"`
+	Metadata  map[string]string `json:"metadata"`
+	CreatedAt time.Time         `json:"created_at"`
+	ExpiresAt time.Time         `json:"expires_at"`
+	// Location the location returned by TusConfig.Storage after the upload completed
+	Location string `json:"location"`
+}
+
+// Completed report whether all the bytes have been uploaded
+func (u *TusUpload) Completed() bool {
+	return u.Offset == u.Size
+}
+
+// TusStore store the uploads in progress
+type TusStore interface {
+	// Create create an empty upload
+	Create(upload *TusUpload) error
+	// Get return the upload with its current offset, or ErrTusNotFound
+	Get(id string) (*TusUpload, error)
+	// Save save the info of the upload
+	Save(upload *TusUpload) error
+	// WriteChunk append the chunk at offset, it returns the number of bytes written,
+	// the bytes written before an error are kept
+	WriteChunk(id string, offset int64, r io.Reader) (int64, error)
+	// Open open the uploaded bytes
+	Open(id string) (io.ReadCloser, error)
+	// Delete delete the upload
+	Delete(id string) error
+	// List return all the uploads
+	List() ([]*TusUpload, error)
+}
+
+// TusConfig config of NewTusHandler
+type TusConfig struct {
+	// Store store the uploads in progress
+	Store TusStore
+
+	// MaxSize the max Upload-Length, 0 means no limit
+	MaxSize int64
+
+	// Expiry an upload expires when it has not been patched for this duration, default 24h,
+	// the expired uploads are deleted by PurgeExpired
+	Expiry time.Duration
+
+	// Storage optional, the completed file is stored into it and the location is saved to the upload,
+	// without Storage the completed file is kept in Store until it is terminated or deleted by OnComplete
+	Storage UploadStorage
+
+	// Name return the name of the file in Storage, the default is the id with the extension of the filename metadata
+	Name func(upload *TusUpload) string
+
+	// OnComplete called after the upload completed and was stored into Storage
+	OnComplete func(c *Context, upload *TusUpload) error
+}
+
+// TusHandler an http.Handler serving the tus protocol, mount it with RouterGroup.Mount
+type TusHandler struct {
+	config TusConfig
+	engine *Engine
+
+	mu   sync.Mutex
+	busy map[string]bool
+}
+
+// NewTusHandler return a TusHandler
+func NewTusHandler(config TusConfig) *TusHandler {
+	assert1(config.Store != nil, "tus store can not be nil")
+	if config.Expiry <= 0 {
+		config.Expiry = defaultTusExpiry
+	}
+	h := &TusHandler{
+		config: config,
+		busy:   make(map[string]bool),
+	}
+
+	engine := New()
+	engine.Use(h.resumable)
+	engine.OPTIONS("/", h.options)
+	engine.POST("/", h.create)
+	engine.HEAD("/{id}", h.head)
+	engine.PATCH("/{id}", h.patch)
+	engine.DELETE("/{id}", h.terminate)
+	h.engine = engine
+	return h
+}
+
+// ServeHTTP implements the http.Handler interface
+func (h *TusHandler) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	h.engine.ServeHTTP(w, req)
+}
+
+// PurgeExpired delete the expired uploads, it returns the number of deleted uploads,
+// the completed uploads are kept when there is no Storage as Store has the only copy of them
+//	go func() {
+//		for range time.Tick(time.Hour) {
+//			tus.PurgeExpired()
+//		}
+//	}()
+func (h *TusHandler) PurgeExpired() (int, error) {
+	uploads, err := h.config.Store.List()
+	if err != nil {
+		return 0, err
+	}
+	now := time.Now()
+	n := 0
+	for _, upload := range uploads {
+		if upload.ExpiresAt.After(now) || upload.Completed() && h.config.Storage == nil || !h.lock(upload.ID) {
+			continue
+		}
+		err = h.config.Store.Delete(upload.ID)
+		h.unlock(upload.ID)
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// resumable check the Tus-Resumable header
+func (h *TusHandler) resumable(c *Context) {
+	c.Header("Tus-Resumable", TusVersion)
+	if c.Request.Method != http.MethodOptions && c.GetHeader("Tus-Resumable") != TusVersion {
+		c.Header("Tus-Version", TusVersion)
+		c.AbortWithStatus(http.StatusPreconditionFailed)
+		return
+	}
+	c.Next()
+}
+
+func (h *TusHandler) options(c *Context) {
+	c.Header("Tus-Version", TusVersion)
+	c.Header("Tus-Extension", tusExtensions)
+	if h.config.MaxSize > 0 {
+		c.Header("Tus-Max-Size", strconv.FormatInt(h.config.MaxSize, 10))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TusHandler) create(c *Context) {
+	size, err := strconv.ParseInt(c.GetHeader("Upload-Length"), 10, 64)
+	if err != nil || size < 0 {
+		c.ServerString(http.StatusBadRequest, "invalid Upload-Length")
+		return
+	}
+	if h.config.MaxSize > 0 && size > h.config.MaxSize {
+		c.ServerString(http.StatusRequestEntityTooLarge, "Upload-Length exceeds Tus-Max-Size")
+		return
+	}
+	metadata, err := parseTusMetadata(c.GetHeader("Upload-Metadata"))
+	if err != nil {
+		c.ServerString(http.StatusBadRequest, "invalid Upload-Metadata")
+		return
+	}
+
+	id := make([]byte, tusUploadIDLength/2)
+	rand.Read(id)
+	now := time.Now()
+	upload := &TusUpload{
+		ID:        hex.EncodeToString(id),
+		Size:      size,
+		Metadata:  metadata,
+		CreatedAt: now,
+		ExpiresAt: now.Add(h.config.Expiry),
+	}
+	if err = h.config.Store.Create(upload); err != nil {
+		c.ServerString(http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	c.Header("Location", strings.TrimSuffix(forwardedPrefix(c)+c.Request.URL.Path, "/")+"/"+upload.ID)
+	// an empty upload is completed when it is created
+	if c.ContentType() == tusContentType || size == 0 {
+		h.lock(upload.ID)
+		defer h.unlock(upload.ID)
+		if code := h.writeChunk(c, upload); code != 0 {
+			c.Status(code)
+			return
+		}
+	}
+	c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	c.Status(http.StatusCreated)
+}
+
+func (h *TusHandler) head(c *Context) {
+	upload, code := h.get(c.Param(tusIDParam))
+	if code != 0 {
+		c.Status(code)
+		return
+	}
+	c.Header("Cache-Control", "no-store")
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+	c.Header("Upload-Length", strconv.FormatInt(upload.Size, 10))
+	c.Header("Upload-Metadata", formatTusMetadata(upload.Metadata))
+	if !upload.Completed() {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	c.Status(http.StatusOK)
+}
+
+func (h *TusHandler) patch(c *Context) {
+	if c.ContentType() != tusContentType {
+		c.Status(http.StatusUnsupportedMediaType)
+		return
+	}
+	offset, err := strconv.ParseInt(c.GetHeader("Upload-Offset"), 10, 64)
+	if err != nil || offset < 0 {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	id := c.Param(tusIDParam)
+	if !h.lock(id) {
+		c.Status(http.StatusLocked)
+		return
+	}
+	defer h.unlock(id)
+
+	upload, code := h.get(id)
+	if code != 0 {
+		c.Status(code)
+		return
+	}
+	if offset != upload.Offset {
+		c.Status(http.StatusConflict)
+		return
+	}
+	if code = h.writeChunk(c, upload); code != 0 {
+		c.Status(code)
+		return
+	}
+	if !upload.Completed() {
+		c.Header("Upload-Expires", upload.ExpiresAt.UTC().Format(http.TimeFormat))
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func (h *TusHandler) terminate(c *Context) {
+	id := c.Param(tusIDParam)
+	if !h.lock(id) {
+		c.Status(http.StatusLocked)
+		return
+	}
+	defer h.unlock(id)
+
+	if _, code := h.get(id); code == http.StatusNotFound {
+		c.Status(code)
+		return
+	}
+	if err := h.config.Store.Delete(id); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// writeChunk append the request body to the upload and finish the completed upload,
+// it returns the error status or 0
+func (h *TusHandler) writeChunk(c *Context, upload *TusUpload) int {
+	// a completed upload is finished again only when storing it failed before
+	if upload.Completed() && upload.Size > 0 && (h.config.Storage == nil || upload.Location != "") {
+		c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+		return 0
+	}
+	remaining := upload.Size - upload.Offset
+	if c.Request.ContentLength > remaining {
+		return http.StatusRequestEntityTooLarge
+	}
+	n, err := h.config.Store.WriteChunk(upload.ID, upload.Offset, io.LimitReader(c.Request.Body, remaining))
+	if err == ErrTusOffset {
+		return http.StatusConflict
+	}
+	if err != nil && n == 0 {
+		debugPrint("[WARNING] tus write chunk error:%v", err)
+		return http.StatusInternalServerError
+	}
+	upload.Offset += n
+	upload.ExpiresAt = time.Now().Add(h.config.Expiry)
+	c.Header("Upload-Offset", strconv.FormatInt(upload.Offset, 10))
+
+	if upload.Completed() {
+		if err = h.finish(c, upload); err != nil {
+			debugPrint("[WARNING] tus finish upload error:%v", err)
+			return http.StatusInternalServerError
+		}
+		return 0
+	}
+	if err = h.config.Store.Save(upload); err != nil {
+		return http.StatusInternalServerError
+	}
+	return 0
+}
+
+// finish store the completed upload into Storage and call OnComplete
+func (h *TusHandler) finish(c *Context, upload *TusUpload) error {
+	if h.config.Storage != nil {
+		f, err := h.config.Store.Open(upload.ID)
+		if err != nil {
+			return err
+		}
+		name := upload.ID + strings.ToLower(filepath.Ext(upload.Metadata["filename"]))
+		if h.config.Name != nil {
+			name = h.config.Name(upload)
+		}
+		upload.Location, err = h.config.Storage.Store(f, name)
+		f.Close()
+		if err != nil {
+			return err
+		}
+	}
+	if err := h.config.Store.Save(upload); err != nil {
+		return err
+	}
+	if h.config.OnComplete != nil {
+		return h.config.OnComplete(c, upload)
+	}
+	return nil
+}
+
+// get return the upload or the error status
+func (h *TusHandler) get(id string) (*TusUpload, int) {
+	if !isTusID(id) {
+		return nil, http.StatusNotFound
+	}
+	upload, err := h.config.Store.Get(id)
+	if err == ErrTusNotFound {
+		return nil, http.StatusNotFound
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError
+	}
+	if upload.ExpiresAt.Before(time.Now()) {
+		return nil, http.StatusGone
+	}
+	return upload, 0
+}
+
+// lock mark the upload busy, it returns false when the upload is already busy
+func (h *TusHandler) lock(id string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.busy[id] {
+		return false
+	}
+	h.busy[id] = true
+	return true
+}
+
+func (h *TusHandler) unlock(id string) {
+	h.mu.Lock()
+	delete(h.busy, id)
+	h.mu.Unlock()
+}
+
+func isTusID(id string) bool {
+	if len(id) != tusUploadIDLength {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// parseTusMetadata parse the Upload-Metadata header
+//	filename d29ybGRfZG9taW5hdGlvbl9wbGFuLnBkZg==,is_confidential
+func parseTusMetadata(header string) (map[string]string, error) {
+	metadata := make(map[string]string)
+	for _, pair := range strings.Split(header, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kv := strings.SplitN(pair, " ", 2)
+		value := ""
+		if len(kv) == 2 {
+			b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(kv[1]))
+			if err != nil {
+				return nil, err
+			}
+			value = string(b)
+		}
+		metadata[kv[0]] = value
+	}
+	return metadata, nil
+}
+
+func formatTusMetadata(metadata map[string]string) string {
+	keys := make([]string, 0, len(metadata))
+	for k := range metadata {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if v := metadata[k]; v != "" {
+			pairs = append(pairs, k+" "+base64.StdEncoding.EncodeToString([]byte(v)))
+		} else {
+			pairs = append(pairs, k)
+		}
+	}
+	return strings.Join(pairs, ",")
+}
+
+// TusFileStore store the uploads in a local directory,
+// the bytes are in {id}.bin and the info is in {id}.info
+type TusFileStore struct {
+	Dir string
+}
+
+// Create create the files of the upload
+func (s *TusFileStore) Create(upload *TusUpload) error {
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path(upload.ID, tusFileSuffix), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+	if err != nil {
+		return err
+	}
+	f.Close()
+	return s.Save(upload)
+}
+
+// Get read the info of the upload, the offset is the size of the uploaded bytes
+func (s *TusFileStore) Get(id string) (*TusUpload, error) {
+	b, err := ioutil.ReadFile(s.path(id, tusInfoSuffix))
+	if os.IsNotExist(err) {
+		return nil, ErrTusNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	upload := new(TusUpload)
+	if err = json.Unmarshal(b, upload); err != nil {
+		return nil, err
+	}
+	fi, err := os.Stat(s.path(id, tusFileSuffix))
+	if os.IsNotExist(err) {
+		return nil, ErrTusNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	upload.Offset = fi.Size()
+	return upload, nil
+}
+
+// Save write the info of the upload
+func (s *TusFileStore) Save(upload *TusUpload) error {
+	b, err := json.Marshal(upload)
+	if err != nil {
+		return err
+	}
+	tmp := s.path(upload.ID, tusInfoSuffix+".tmp")
+	if err = ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(upload.ID, tusInfoSuffix))
+}
+
+// WriteChunk append the chunk to {id}.bin
+func (s *TusFileStore) WriteChunk(id string, offset int64, r io.Reader) (int64, error) {
+	f, err := os.OpenFile(s.path(id, tusFileSuffix), os.O_WRONLY|os.O_APPEND, 0644)
+	if os.IsNotExist(err) {
+		return 0, ErrTusNotFound
+	}
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if fi.Size() != offset {
+		return 0, ErrTusOffset
+	}
+	return io.Copy(f, r)
+}
+
+// Open open {id}.bin
+func (s *TusFileStore) Open(id string) (io.ReadCloser, error) {
+	f, err := os.Open(s.path(id, tusFileSuffix))
+	if os.IsNotExist(err) {
+		return nil, ErrTusNotFound
+	}
+	return f, err
+}
+
+// Delete remove the files of the upload
+func (s *TusFileStore) Delete(id string) error {
+	for _, suffix := range []string{tusFileSuffix, tusInfoSuffix} {
+		if err := os.Remove(s.path(id, suffix)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// List read the info of all the uploads
+func (s *TusFileStore) List() ([]*TusUpload, error) {
+	names, err := filepath.Glob(filepath.Join(s.Dir, "*"+tusInfoSuffix))
+	if err != nil {
+		return nil, err
+	}
+	uploads := make([]*TusUpload, 0, len(names))
+	for _, name := range names {
+		upload, err := s.Get(strings.TrimSuffix(filepath.Base(name), tusInfoSuffix))
+		if err == ErrTusNotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, upload)
+	}
+	return uploads, nil
+}
+
+func (s *TusFileStore) path(id, suffix string) string {
+	return filepath.Join(s.Dir, id+suffix)
+}
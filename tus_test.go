@@ -0,0 +1,170 @@
+package gow
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTusHandler(t *testing.T) {
+	store := &TusFileStore{Dir: t.TempDir()}
+	dir := t.TempDir()
+	var completed *TusUpload
+	tus := NewTusHandler(TusConfig{
+		Store:   store,
+		MaxSize: 100,
+		Storage: &LocalStorage{Dir: dir, URLPrefix: "/video/"},
+		OnComplete: func(c *Context, upload *TusUpload) error {
+			completed = upload
+			return nil
+		},
+	})
+	r := New()
+	r.Mount("/files", tus)
+
+	do := func(method, path, body string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Tus-Resumable", TusVersion)
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do("OPTIONS", "/files", "")
+	if w.Code != 204 || w.Header().Get("Tus-Max-Size") != "100" || !strings.Contains(w.Header().Get("Tus-Extension"), "creation") {
+		t.Errorf("options = %d %v", w.Code, w.Header())
+	}
+	if w = do("POST", "/files", "", "Upload-Length", "101"); w.Code != 413 {
+		t.Errorf("too large = %d", w.Code)
+	}
+	req := httptest.NewRequest("POST", "/files", nil)
+	req.Header.Set("Upload-Length", "11")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 412 || w.Header().Get("Tus-Version") != TusVersion {
+		t.Errorf("without Tus-Resumable = %d", w.Code)
+	}
+
+	w = do("POST", "/files", "", "Upload-Length", "11", "Upload-Metadata", "filename bW92aWUubXA0,private")
+	location := w.Header().Get("Location")
+	if w.Code != 201 || !strings.HasPrefix(location, "/files/") || w.Header().Get("Upload-Expires") == "" {
+		t.Fatalf("create = %d %v", w.Code, w.Header())
+	}
+	id := strings.TrimPrefix(location, "/files/")
+
+	w = do("HEAD", location, "")
+	if w.Code != 200 || w.Header().Get("Upload-Offset") != "0" || w.Header().Get("Upload-Length") != "11" ||
+		w.Header().Get("Upload-Metadata") != "filename bW92aWUubXA0,private" {
+		t.Errorf("head = %d %v", w.Code, w.Header())
+	}
+
+	tests := []struct {
+		offset, body, contentType string
+		code                      int
+		newOffset                 string
+	}{
+		{"0", "hello ", tusContentType, 204, "6"},
+		{"0", "hello ", tusContentType, 409, ""},
+		{"6", "world", "text/plain", 415, ""},
+		{"6", "world!", tusContentType, 413, ""},
+		{"6", "world", tusContentType, 204, "11"},
+	}
+	for _, tt := range tests {
+		w = do("PATCH", location, tt.body, "Upload-Offset", tt.offset, "Content-Type", tt.contentType)
+		if w.Code != tt.code || w.Header().Get("Upload-Offset") != tt.newOffset {
+			t.Errorf("patch %s %q = %d, offset %q", tt.offset, tt.body, w.Code, w.Header().Get("Upload-Offset"))
+		}
+	}
+
+	if completed == nil || completed.Location != "/video/"+id+".mp4" {
+		t.Fatalf("completed = %+v", completed)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, id+".mp4")); string(b) != "hello world" {
+		t.Errorf("stored = %q", b)
+	}
+
+	if w = do("DELETE", location, ""); w.Code != 204 {
+		t.Errorf("delete = %d", w.Code)
+	}
+	if w = do("HEAD", location, ""); w.Code != http.StatusNotFound {
+		t.Errorf("head after delete = %d", w.Code)
+	}
+}
+
+func TestTusHandlerExpiry(t *testing.T) {
+	store := &TusFileStore{Dir: t.TempDir()}
+	tus := NewTusHandler(TusConfig{Store: store})
+	r := New()
+	r.Mount("/files", tus)
+
+	req := httptest.NewRequest("POST", "/files", strings.NewReader("abc"))
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Upload-Length", "10")
+	req.Header.Set("Content-Type", tusContentType)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != 201 || w.Header().Get("Upload-Offset") != "3" {
+		t.Fatalf("create with upload = %d %v", w.Code, w.Header())
+	}
+	id := strings.TrimPrefix(w.Header().Get("Location"), "/files/")
+
+	req = httptest.NewRequest("POST", "/files", strings.NewReader("abc"))
+	req.Header.Set("Tus-Resumable", TusVersion)
+	req.Header.Set("Upload-Length", "3")
+	req.Header.Set("Content-Type", tusContentType)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	done := strings.TrimPrefix(w.Header().Get("Location"), "/files/")
+
+	for _, id := range []string{id, done} {
+		upload, _ := store.Get(id)
+		upload.ExpiresAt = time.Now().Add(-time.Second)
+		store.Save(upload)
+	}
+
+	req = httptest.NewRequest("HEAD", "/files/"+id, nil)
+	req.Header.Set("Tus-Resumable", TusVersion)
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	if w.Code != http.StatusGone {
+		t.Errorf("expired head = %d", w.Code)
+	}
+	// the completed upload is kept as there is no Storage
+	if n, err := tus.PurgeExpired(); n != 1 || err != nil {
+		t.Errorf("purged = %d, %v", n, err)
+	}
+	if uploads, _ := store.List(); len(uploads) != 1 || uploads[0].ID != done {
+		t.Errorf("%d uploads left", len(uploads))
+	}
+}
+
+func TestTusHandlerForwardedPrefix(t *testing.T) {
+	tus := NewTusHandler(TusConfig{Store: &TusFileStore{Dir: t.TempDir()}})
+	tests := []struct {
+		remote string
+		prefix string
+	}{
+		{"203.0.113.7:1234", ""},
+		{"127.0.0.1:1234", "/api/files"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/", nil)
+		req.RemoteAddr = tt.remote
+		req.Header.Set("Tus-Resumable", TusVersion)
+		req.Header.Set("Upload-Length", "10")
+		req.Header.Set("X-Forwarded-Prefix", "/api/files")
+		w := httptest.NewRecorder()
+		tus.ServeHTTP(w, req)
+		location := w.Header().Get("Location")
+		if w.Code != 201 || !isTusID(strings.TrimPrefix(location, tt.prefix+"/")) {
+			t.Errorf("%s: location = %q", tt.remote, location)
+		}
+	}
+}
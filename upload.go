@@ -0,0 +1,392 @@
+package gow
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gkzy/gow/lib/logy"
+)
+
+// upload errors, they are wrapped in *UploadError
+var (
+	ErrUploadTooLarge = errors.New("the file is too large")
+	ErrUploadTooMany  = errors.New("too many files")
+	ErrUploadType     = errors.New("the file type is not allowed")
+	ErrUploadMissing  = errors.New("the file is required")
+	ErrUploadField    = errors.New("the field does not accept files")
+)
+
+// UploadRule the limits of the files of a form field
+type UploadRule struct {
+	// MaxSize the max size in bytes of each file, 0 means no limit
+	MaxSize int64
+
+	// MaxCount the max number of files, default 1
+	MaxCount int
+
+	// Required at least one file must be uploaded
+	Required bool
+
+	// AllowedTypes the MIME types sniffed from the content, like image/png or image/*,
+	// the extension and Content-Type sent by the client are not trusted, empty allows all types
+	AllowedTypes []string
+}
+
+// UploadConfig config of Context.Upload
+type UploadConfig struct {
+	// Fields the rules of the file fields, files of other fields are rejected
+	Fields map[string]UploadRule
+
+	// Storage store the files after all of them are validated
+	Storage UploadStorage
+
+	// Name return the name of the file in the storage, it should be unique as a failed upload removes the files it stored,
+	// the default is the sha256 of the content and a random suffix with the extension of the sniffed type
+	Name func(file *UploadedFile) string
+
+	// TempDir the directory of the temp files, default os.TempDir()
+	TempDir string
+}
+
+// UploadedFile the result of an uploaded file
+type UploadedFile struct {
+	Field       string `json:"field"`
+	Filename    string `json:"filename"`
+	Size        int64  `json:"size"`
+	ContentType string `json:"content_type"`
+	SHA256      string `json:"sha256"`
+	Location    string `json:"location"`
+
+	tmp string
+}
+
+// UploadedFiles the uploaded files by field
+type UploadedFiles map[string][]*UploadedFile
+
+// First return the first file of the field or nil
+func (files UploadedFiles) First(field string) *UploadedFile {
+	if len(files[field]) == 0 {
+		return nil
+	}
+	return files[field][0]
+}
+
+// UploadError the upload of a field failed
+type UploadError struct {
+	Field    string
+	Filename string
+	Err      error
+}
+
+func (e *UploadError) Error() string {
+	if e.Filename != "" {
+		return fmt.Sprintf("upload %s %q: %v", e.Field, e.Filename, e.Err)
+	}
+	return fmt.Sprintf("upload %s: %v", e.Field, e.Err)
+}
+
+func (e *UploadError) Unwrap() error {
+	return e.Err
+}
+
+// UploadStorage store the uploaded files
+type UploadStorage interface {
+	// Store save the content of r as name, it returns the location of the stored file
+	Store(r io.Reader, name string) (location string, err error)
+	// Remove delete the stored file by its location, it is called for the stored files of a failed upload
+	Remove(location string) error
+}
+
+// LocalStorage store the uploaded files in a local directory
+//	the location is URLPrefix + name
+type LocalStorage struct {
+	Dir       string
+	URLPrefix string
+}
+
+// Store save the file into the directory, the directories of name are created
+func (s *LocalStorage) Store(r io.Reader, name string) (string, error) {
+	name = filepath.ToSlash(filepath.Clean("/" + name))[1:]
+	if name == "" {
+		return "", errors.New("upload: the file name is empty")
+	}
+	path := filepath.Join(s.Dir, filepath.FromSlash(name))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", err
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", err
+	}
+	if _, err = io.Copy(f, r); err != nil {
+		f.Close()
+		os.Remove(path)
+		return "", err
+	}
+	if err = f.Close(); err != nil {
+		return "", err
+	}
+	return s.URLPrefix + name, nil
+}
+
+// Remove delete the file of the location returned by Store
+func (s *LocalStorage) Remove(location string) error {
+	name := filepath.ToSlash(filepath.Clean("/" + strings.TrimPrefix(location, s.URLPrefix)))[1:]
+	return os.Remove(filepath.Join(s.Dir, filepath.FromSlash(name)))
+}
+
+// Upload read the multipart files, validate them and save them to the storage
+//	the files are streamed into temp files while their size, type and hash are checked,
+//	nothing is stored unless all the files are valid, the temp files are always removed,
+//	when a file fails to be stored the files already stored are removed from the storage
+//	files, err := c.Upload(gow.UploadConfig{
+//		Fields: map[string]gow.UploadRule{
+//			"avatar": {MaxSize: 2 << 20, Required: true, AllowedTypes: []string{"image/*"}},
+//		},
+//		Storage: &gow.LocalStorage{Dir: "./static/upload", URLPrefix: "/static/upload/"},
+//	})
+func (c *Context) Upload(config UploadConfig) (UploadedFiles, error) {
+	assert1(config.Storage != nil, "upload storage can not be nil")
+
+	u := &uploader{config: config, files: make(UploadedFiles)}
+	defer u.cleanup()
+
+	if err := u.read(c); err != nil {
+		return nil, err
+	}
+	for field, rule := range config.Fields {
+		if rule.Required && len(u.files[field]) == 0 {
+			return nil, &UploadError{Field: field, Err: ErrUploadMissing}
+		}
+	}
+	for i, file := range u.all {
+		if err := u.store(file); err != nil {
+			for _, stored := range u.all[:i] {
+				if rmErr := config.Storage.Remove(stored.Location); rmErr != nil {
+					logy.Errorf("[%s] upload: remove %s: %v", c.engine.AppName, stored.Location, rmErr)
+				}
+			}
+			return nil, &UploadError{Field: file.Field, Filename: file.Filename, Err: err}
+		}
+	}
+	return u.files, nil
+}
+
+type uploader struct {
+	config UploadConfig
+	files  UploadedFiles
+	all    []*UploadedFile
+	temps  []string
+}
+
+// read stream the file parts into temp files, the other parts are set to the request form
+func (u *uploader) read(c *Context) error {
+	if form := c.Request.MultipartForm; form != nil {
+		for field, headers := range form.File {
+			for _, fh := range headers {
+				f, err := fh.Open()
+				if err != nil {
+					return err
+				}
+				err = u.readFile(field, fh.Filename, f)
+				f.Close()
+				if err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	mr, err := c.Request.MultipartReader()
+	if err != nil {
+		return err
+	}
+	values := make(url.Values)
+	remaining := c.engine.MaxMultipartMemory
+	for {
+		part, err := mr.NextPart()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		field := part.FormName()
+		if field == "" {
+			part.Close()
+			continue
+		}
+		if part.FileName() == "" {
+			b, err := ioutil.ReadAll(io.LimitReader(part, remaining+1))
+			part.Close()
+			if err != nil {
+				return err
+			}
+			if remaining -= int64(len(b)); remaining < 0 {
+				return multipart.ErrMessageTooLarge
+			}
+			values.Add(field, string(b))
+			continue
+		}
+		err = u.readFile(field, part.FileName(), part)
+		part.Close()
+		if err != nil {
+			return err
+		}
+	}
+
+	c.Request.MultipartForm = &multipart.Form{Value: values, File: map[string][]*multipart.FileHeader{}}
+	if c.Request.PostForm == nil {
+		c.Request.PostForm = make(url.Values)
+	}
+	if c.Request.Form == nil {
+		c.Request.Form = c.Request.URL.Query()
+	}
+	for k, v := range values {
+		c.Request.PostForm[k] = append(c.Request.PostForm[k], v...)
+		c.Request.Form[k] = append(c.Request.Form[k], v...)
+	}
+	return nil
+}
+
+// readFile check the rule of the field and copy the file into a temp file
+func (u *uploader) readFile(field, filename string, r io.Reader) error {
+	uploadErr := func(err error) error {
+		return &UploadError{Field: field, Filename: filename, Err: err}
+	}
+	rule, ok := u.config.Fields[field]
+	if !ok {
+		return uploadErr(ErrUploadField)
+	}
+	maxCount := rule.MaxCount
+	if maxCount <= 0 {
+		maxCount = 1
+	}
+	if len(u.files[field]) >= maxCount {
+		return uploadErr(ErrUploadTooMany)
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return uploadErr(err)
+	}
+	head = head[:n]
+	contentType, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	if !allowedUploadType(rule.AllowedTypes, contentType) {
+		return uploadErr(ErrUploadType)
+	}
+
+	tmp, err := ioutil.TempFile(u.config.TempDir, "gow-upload-")
+	if err != nil {
+		return uploadErr(err)
+	}
+	u.temps = append(u.temps, tmp.Name())
+	defer tmp.Close()
+
+	src := io.MultiReader(bytes.NewReader(head), r)
+	if rule.MaxSize > 0 {
+		src = io.LimitReader(src, rule.MaxSize+1)
+	}
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, hash), src)
+	if err != nil {
+		return uploadErr(err)
+	}
+	if rule.MaxSize > 0 && size > rule.MaxSize {
+		return uploadErr(ErrUploadTooLarge)
+	}
+
+	file := &UploadedFile{
+		Field:       field,
+		Filename:    filepath.Base(filename),
+		Size:        size,
+		ContentType: contentType,
+		SHA256:      hex.EncodeToString(hash.Sum(nil)),
+		tmp:         tmp.Name(),
+	}
+	u.files[field] = append(u.files[field], file)
+	u.all = append(u.all, file)
+	return tmp.Close()
+}
+
+func (u *uploader) store(file *UploadedFile) error {
+	f, err := os.Open(file.tmp)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	name := defaultUploadName(file)
+	if u.config.Name != nil {
+		name = u.config.Name(file)
+	}
+	file.Location, err = u.config.Storage.Store(f, name)
+	return err
+}
+
+func (u *uploader) cleanup() {
+	for _, tmp := range u.temps {
+		os.Remove(tmp)
+	}
+}
+
+// uploadExtensions the extensions of the common sniffed types
+var uploadExtensions = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/gif":       ".gif",
+	"image/webp":      ".webp",
+	"image/bmp":       ".bmp",
+	"application/pdf": ".pdf",
+	"application/zip": ".zip",
+	"video/mp4":       ".mp4",
+	"video/webm":      ".webm",
+	"audio/mpeg":      ".mp3",
+	"audio/wave":      ".wav",
+	"text/plain":      ".txt",
+}
+
+// defaultUploadName the sha256 of the file and a random suffix with the extension of the sniffed type,
+// the suffix keeps the rollback of a failed upload from removing the same content stored by another upload,
+// the extension of the client file name is used when the type has no known extension
+func defaultUploadName(file *UploadedFile) string {
+	suffix := make([]byte, 8)
+	rand.Read(suffix)
+	ext, ok := uploadExtensions[file.ContentType]
+	if !ok {
+		ext = strings.ToLower(filepath.Ext(file.Filename))
+		for _, r := range strings.TrimPrefix(ext, ".") {
+			if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9') {
+				ext = ""
+				break
+			}
+		}
+	}
+	return file.SHA256 + "-" + hex.EncodeToString(suffix) + ext
+}
+
+func allowedUploadType(allowed []string, contentType string) bool {
+	if len(allowed) == 0 {
+		return true
+	}
+	for _, t := range allowed {
+		if matchMIME(t, contentType) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,211 @@
+package gow
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var uploadTestPNG = append([]byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), make([]byte, 64)...)
+
+type uploadTestPart struct {
+	field, filename string
+	content         []byte
+}
+
+func TestContextUpload(t *testing.T) {
+	dir, tmp := t.TempDir(), t.TempDir()
+	var (
+		files UploadedFiles
+		err   error
+		nick  string
+	)
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		files, err = c.Upload(UploadConfig{
+			Fields: map[string]UploadRule{
+				"avatar": {MaxSize: 1024, Required: true, AllowedTypes: []string{"image/*"}},
+				"photo":  {MaxCount: 2, AllowedTypes: []string{"image/png"}},
+			},
+			Storage: &LocalStorage{Dir: dir, URLPrefix: "/upload/"},
+			TempDir: tmp,
+		})
+		nick = c.GetString("nick")
+	})
+	upload := func(parts ...uploadTestPart) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		mw.WriteField("nick", "sam")
+		for _, p := range parts {
+			fw, _ := mw.CreateFormFile(p.field, p.filename)
+			fw.Write(p.content)
+		}
+		mw.Close()
+		req := httptest.NewRequest("POST", "/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		files, err = nil, nil
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	upload(uploadTestPart{"avatar", "a.gif", uploadTestPNG}, uploadTestPart{"photo", "1.png", uploadTestPNG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	avatar := files.First("avatar")
+	if avatar == nil || avatar.ContentType != "image/png" || avatar.Size != int64(len(uploadTestPNG)) ||
+		avatar.Filename != "a.gif" || !strings.HasPrefix(avatar.Location, "/upload/"+avatar.SHA256+"-") ||
+		!strings.HasSuffix(avatar.Location, ".png") || len(files["photo"]) != 1 || files.First("photo").Location == avatar.Location {
+		t.Errorf("files = %+v", avatar)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, strings.TrimPrefix(avatar.Location, "/upload/"))); !bytes.Equal(b, uploadTestPNG) {
+		t.Errorf("stored %d bytes", len(b))
+	}
+	if nick != "sam" {
+		t.Errorf("nick = %q", nick)
+	}
+
+	tests := []struct {
+		parts []uploadTestPart
+		err   error
+	}{
+		{[]uploadTestPart{{"avatar", "a.png", []byte("<?php echo 1;")}}, ErrUploadType},
+		{[]uploadTestPart{{"avatar", "a.png", append(uploadTestPNG, make([]byte, 1024)...)}}, ErrUploadTooLarge},
+		{[]uploadTestPart{{"avatar", "a.png", uploadTestPNG}, {"avatar", "b.png", uploadTestPNG}}, ErrUploadTooMany},
+		{[]uploadTestPart{{"photo", "1.png", uploadTestPNG}}, ErrUploadMissing},
+		{[]uploadTestPart{{"other", "1.png", uploadTestPNG}}, ErrUploadField},
+	}
+	for _, tt := range tests {
+		upload(tt.parts...)
+		var uploadErr *UploadError
+		if !errors.Is(err, tt.err) || !errors.As(err, &uploadErr) || files != nil {
+			t.Errorf("%s: err = %v, want %v", tt.parts[0].filename, err, tt.err)
+		}
+	}
+
+	if entries, _ := ioutil.ReadDir(tmp); len(entries) != 0 {
+		t.Errorf("%d temp files are left", len(entries))
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("%d files are stored, want 2", len(entries))
+	}
+}
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	s := &LocalStorage{Dir: dir}
+	loc, err := s.Store(strings.NewReader("x"), "../../2021/a.txt")
+	if err != nil || loc != "2021/a.txt" {
+		t.Fatalf("location = %q, %v", loc, err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2021", "a.txt")); err != nil {
+		t.Error(err)
+	}
+	if err := s.Remove(loc); err != nil {
+		t.Error(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "2021", "a.txt")); !os.IsNotExist(err) {
+		t.Errorf("stat removed file = %v", err)
+	}
+}
+
+// uploadTestStorage fails to store the files whose name ends with fail
+type uploadTestStorage struct {
+	LocalStorage
+	fail string
+}
+
+func (s *uploadTestStorage) Store(r io.Reader, name string) (string, error) {
+	if s.fail != "" && strings.HasSuffix(name, s.fail) {
+		return "", errors.New("disk is full")
+	}
+	return s.LocalStorage.Store(r, name)
+}
+
+func TestContextUploadStoreError(t *testing.T) {
+	dir := t.TempDir()
+	var err error
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		_, err = c.Upload(UploadConfig{
+			Fields:  map[string]UploadRule{"photo": {MaxCount: 3}},
+			Storage: &uploadTestStorage{LocalStorage: LocalStorage{Dir: dir, URLPrefix: "/upload/"}, fail: "3.png"},
+			Name: func(file *UploadedFile) string {
+				return file.Filename
+			},
+		})
+	})
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	for _, name := range []string{"1.png", "2.png", "3.png"} {
+		fw, _ := mw.CreateFormFile("photo", name)
+		fw.Write(uploadTestPNG)
+	}
+	mw.Close()
+	req := httptest.NewRequest("POST", "/upload", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	r.ServeHTTP(httptest.NewRecorder(), req)
+
+	var uploadErr *UploadError
+	if !errors.As(err, &uploadErr) || uploadErr.Filename != "3.png" {
+		t.Errorf("err = %v", err)
+	}
+	// the files stored before the failure are removed
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 0 {
+		t.Errorf("%d files are left", len(entries))
+	}
+}
+
+func TestContextUploadRollbackSameContent(t *testing.T) {
+	dir := t.TempDir()
+	storage := &uploadTestStorage{LocalStorage: LocalStorage{Dir: dir}}
+	var (
+		files UploadedFiles
+		err   error
+	)
+	r := New()
+	r.POST("/upload", func(c *Context) {
+		files, err = c.Upload(UploadConfig{
+			Fields:  map[string]UploadRule{"photo": {MaxCount: 2}},
+			Storage: storage,
+		})
+	})
+	upload := func(parts ...uploadTestPart) {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		for _, p := range parts {
+			fw, _ := mw.CreateFormFile(p.field, p.filename)
+			fw.Write(p.content)
+		}
+		mw.Close()
+		req := httptest.NewRequest("POST", "/upload", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		files, err = nil, nil
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	upload(uploadTestPart{"photo", "1.png", uploadTestPNG})
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := files.First("photo").Location
+
+	// the same content is stored again before the second file fails
+	storage.fail = ".txt"
+	upload(uploadTestPart{"photo", "1.png", uploadTestPNG}, uploadTestPart{"photo", "2.txt", []byte("hello")})
+	if err == nil {
+		t.Fatal("the second upload did not fail")
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, first)); !bytes.Equal(b, uploadTestPNG) {
+		t.Errorf("the file of the first upload is removed, %d bytes left", len(b))
+	}
+	if entries, _ := ioutil.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("%d files are stored, want 1", len(entries))
+	}
+}
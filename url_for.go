@@ -0,0 +1,131 @@
+/*
+named routes and reverse url generation
+	r.GET("/user/{uid:int}", handler).Name("user.show")
+	url, err := r.URLFor("user.show", "uid", 1, "tab", "info") // /user/1?tab=info
+in html templates
+	{{urlfor "user.show" "uid" 1}}
+*/
+
+package gow
+
+import (
+	"fmt"
+	"html/template"
+	"net/url"
+	"strings"
+
+	"github.com/gkzy/gow/render"
+)
+
+// addRouteName names the route n
+func (engine *Engine) addRouteName(name string, n *muxNode) {
+	assert1(name != "", "route name can not be empty")
+	if exist, ok := engine.namedRoutes[name]; ok && exist.fullPath != n.fullPath {
+		panic("route name '" + name + "' is already registered for path '" + exist.fullPath + "'")
+	}
+	engine.namedRoutes[name] = n
+	n.name = name
+}
+
+// newHTMLRender build the html render, urlfor of the templates resolves the routes of this engine
+func (engine *Engine) newHTMLRender() render.Render {
+	if engine.FuncMap == nil {
+		engine.FuncMap = template.FuncMap{}
+	}
+	if _, ok := engine.FuncMap["urlfor"]; !ok {
+		engine.FuncMap["urlfor"] = engine.URLFor
+	}
+	return render.HTMLRender{}.NewHTMLRender(engine.viewsPath, engine.FuncMap, engine.delims, engine.AutoRender, engine.RunMode)
+}
+
+// URLFor builds the url of a named route.
+// params are key value pairs, the keys not used by the route path are added to the query string.
+//	r.GET("/user/{uid:int}", handler).Name("user.show")
+//	r.URLFor("user.show", "uid", 1)               // /user/1
+//	r.URLFor("user.show", "uid", 1, "tab", "info") // /user/1?tab=info
+func (engine *Engine) URLFor(name string, params ...interface{}) (string, error) {
+	n, ok := engine.namedRoutes[name]
+	if !ok {
+		return "", fmt.Errorf("route %s not found", name)
+	}
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("params of route %s must be key value pairs", name)
+	}
+	values := make(map[string]string, len(params)/2)
+	keys := make([]string, 0, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		key := fmt.Sprint(params[i])
+		if _, ok := values[key]; !ok {
+			keys = append(keys, key)
+		}
+		values[key] = fmt.Sprint(params[i+1])
+	}
+
+	used := make(map[string]bool)
+	value := func(key string) (string, error) {
+		v, ok := values[key]
+		if !ok {
+			return "", fmt.Errorf("param %s of route %s is missing", key, name)
+		}
+		if typ := n.paramTypes[key]; typ != nil && !typ.match(v) {
+			return "", fmt.Errorf("param %s=%s does not match route %s", key, v, n.fullPath)
+		}
+		used[key] = true
+		return v, nil
+	}
+
+	var buf strings.Builder
+	for _, seg := range splitMuxPath(n.fullPath) {
+		buf.WriteByte('/')
+		switch {
+		case seg[0] == '*':
+			v, ok := values[seg[1:]]
+			if !ok {
+				return "", fmt.Errorf("param %s of route %s is missing", seg[1:], name)
+			}
+			used[seg[1:]] = true
+			parts := strings.Split(strings.TrimPrefix(v, "/"), "/")
+			for i, part := range parts {
+				parts[i] = url.PathEscape(part)
+			}
+			buf.WriteString(strings.Join(parts, "/"))
+		case strings.Contains(seg, "{"):
+			rest := seg
+			for {
+				start := strings.IndexByte(rest, '{')
+				if start < 0 {
+					buf.WriteString(rest)
+					break
+				}
+				end := start + closingBrace(rest[start:])
+				buf.WriteString(rest[:start])
+				key := rest[start+1 : end]
+				if i := strings.IndexByte(key, ':'); i >= 0 {
+					key = key[:i]
+				}
+				v, err := value(key)
+				if err != nil {
+					return "", err
+				}
+				buf.WriteString(url.PathEscape(v))
+				rest = rest[end+1:]
+			}
+		default:
+			buf.WriteString(seg)
+		}
+	}
+	if buf.Len() == 0 || strings.HasSuffix(n.fullPath, "/") {
+		buf.WriteByte('/')
+	}
+
+	query := url.Values{}
+	for _, key := range keys {
+		if !used[key] {
+			query.Set(key, values[key])
+		}
+	}
+	if len(query) > 0 {
+		buf.WriteString("?" + query.Encode())
+	}
+	return buf.String(), nil
+}
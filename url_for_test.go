@@ -0,0 +1,70 @@
+package gow
+
+import "testing"
+
+func TestEngineURLFor(t *testing.T) {
+	r := New()
+	h := func(c *Context) {}
+	r.GET("/", h).Name("home")
+	v1 := r.Group("/api/v1")
+	v1.GET("/user/{uid:int}", h).Name("user.show")
+	v1.GET("/topic/{name}/read_{tid:int}.html", h).Name("topic.read")
+	v1.GET("/files/*filepath", h).Name("files")
+	v1.GET("/search/{q:[^.]+}", h).Name("search")
+
+	tests := []struct {
+		name   string
+		params []interface{}
+		url    string
+		err    bool
+	}{
+		{"home", nil, "/", false},
+		{"user.show", []interface{}{"uid", 1}, "/api/v1/user/1", false},
+		{"user.show", []interface{}{"uid", 1, "tab", "info"}, "/api/v1/user/1?tab=info", false},
+		{"topic.read", []interface{}{"name", "go", "tid", 12}, "/api/v1/topic/go/read_12.html", false},
+		{"files", []interface{}{"filepath", "/css/a b.css"}, "/api/v1/files/css/a%20b.css", false},
+		{"search", []interface{}{"q", "a/b?"}, "/api/v1/search/a%2Fb%3F", false},
+		{"user.show", []interface{}{"uid", "sam"}, "", true},
+		{"user.show", nil, "", true},
+		{"user.show", []interface{}{"uid"}, "", true},
+		{"nothing", nil, "", true},
+	}
+	for _, tt := range tests {
+		url, err := r.URLFor(tt.name, tt.params...)
+		if (err != nil) != tt.err || url != tt.url {
+			t.Errorf("URLFor(%s, %v) = %q, %v, want %q", tt.name, tt.params, url, err, tt.url)
+		}
+	}
+
+	for _, route := range r.Routes() {
+		if route.Path == "/api/v1/user/{uid:int}" && route.Name != "user.show" {
+			t.Errorf("route name = %q, want user.show", route.Name)
+		}
+	}
+}
+
+func TestEngineRouteNameOfNode(t *testing.T) {
+	r := New()
+	h := func(c *Context) {}
+	r.GET("/user/{uid}", h).Name("user.show")
+	r.POST("/user/{uid}", h)
+	for _, route := range r.Routes() {
+		if want := map[string]string{"GET": "user.show"}[route.Method]; route.Name != want {
+			t.Errorf("%s %s name = %q, want %q", route.Method, route.Path, route.Name, want)
+		}
+	}
+}
+
+func TestEngineURLForFuncMap(t *testing.T) {
+	a, b := New(), New()
+	a.GET("/a/{id}", func(c *Context) {}).Name("show")
+	b.GET("/b/{id}", func(c *Context) {}).Name("show")
+	a.newHTMLRender()
+	b.newHTMLRender()
+	for want, r := range map[string]*Engine{"/a/1": a, "/b/1": b} {
+		urlfor := r.FuncMap["urlfor"].(func(string, ...interface{}) (string, error))
+		if url, err := urlfor("show", "id", 1); err != nil || url != want {
+			t.Errorf("urlfor = %q, %v, want %q", url, err, want)
+		}
+	}
+}
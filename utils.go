@@ -9,6 +9,7 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"sort"
 	"strconv"
 	"strings"
 	"unsafe"
@@ -128,15 +129,51 @@ func chooseData(custom, wildcard interface{}) interface{} {
 	panic("negotiation config is invalid")
 }
 
+// parseAccept returns the values of an Accept like header ordered by q,
+// the values with q=0 are dropped
+//	text/html, application/json;q=0.9, */*;q=0.8
 func parseAccept(acceptHeader string) []string {
+	accepted, _ := parseAcceptQ(acceptHeader)
+	return accepted
+}
+
+// parseAcceptQ returns the accepted values ordered by q and the refused values with q=0
+func parseAcceptQ(acceptHeader string) (accepted, refused []string) {
+	type accept struct {
+		value string
+		q     float64
+	}
 	parts := strings.Split(acceptHeader, ",")
-	out := make([]string, 0, len(parts))
+	accepts := make([]accept, 0, len(parts))
 	for _, part := range parts {
-		if part = strings.TrimSpace(strings.Split(part, ";")[0]); part != "" {
-			out = append(out, part)
+		params := strings.Split(part, ";")
+		value := strings.TrimSpace(params[0])
+		if value == "" {
+			continue
 		}
+		q := 1.0
+		for _, param := range params[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if v, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+		if q > 0 {
+			accepts = append(accepts, accept{value: value, q: q})
+		} else {
+			refused = append(refused, value)
+		}
+	}
+	sort.SliceStable(accepts, func(i, j int) bool {
+		return accepts[i].q > accepts[j].q
+	})
+	accepted = make([]string, 0, len(accepts))
+	for _, a := range accepts {
+		accepted = append(accepted, a.value)
 	}
-	return out
+	return accepted, refused
 }
 
 func lastChar(str string) uint8 {
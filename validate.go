@@ -0,0 +1,522 @@
+/*
+declarative validation by the validate tags, it runs after binding
+	type UserRequest struct {
+		Mobile string   `json:"mobile" validate:"required,mobile" label:"手机号"`
+		IDCard string   `json:"id_card" validate:"omitempty,idcard"`
+		Age    int      `json:"age" validate:"min=18,max=120"`
+		Type   string   `json:"type" validate:"in=a|b|c"`
+		Tags   []string `json:"tags" validate:"max=5"`
+	}
+	if err := c.ShouldBind(&req); err != nil {
+		c.DataJSON(err) // ValidationErrors are written with engine.ValidationErrorCode
+		return
+	}
+rules
+	required        not the zero value
+	omitempty       skips the other rules for the zero value
+	min=1 max=10    number value, or length of string, slice and map
+	len=11          length, or number value
+	in=a|b|c        one of
+	email mobile idcard url numeric
+custom rules
+	r.RegisterValidation("objectid", gow.ValidationRule{...})
+messages are zh or en by Accept-Language, engine.ValidationLang by default.
+the tags of a type are checked once, an unknown rule or an invalid param is returned as an error
+*/
+
+package gow
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	validationLangZH = "zh"
+	validationLangEN = "en"
+)
+
+// ValidationRule defines a custom validation rule used as validate:"name" or validate:"name=param"
+type ValidationRule struct {
+	// Func reports whether the value is valid, pointers are already dereferenced
+	Func func(v reflect.Value, param string) bool
+
+	// Messages are keyed by language, like zh and en, {field} and {param} are replaced.
+	//	map[string]string{"zh": "{field}格式不正确", "en": "{field} is invalid"}
+	Messages map[string]string
+}
+
+// FieldError is the failure of a single field
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors are the field errors of a struct
+type ValidationErrors []*FieldError
+
+func (es ValidationErrors) Error() string {
+	messages := make([]string, 0, len(es))
+	for _, e := range es {
+		messages = append(messages, e.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validationRule is a compiled validation rule
+type validationRule struct {
+	fn       func(v reflect.Value, param string) bool
+	messages map[string]string
+	// lenMessages are used for the length of strings, slices and maps
+	lenMessages map[string]string
+	// numberParam is set when the param must be a number
+	numberParam bool
+}
+
+// validationRules is the validation rule registry of an engine
+type validationRules struct {
+	rules map[string]*validationRule
+	// checked caches the result of checkType by the struct type
+	checked sync.Map
+}
+
+var (
+	emailRegexp   = regexp.MustCompile(`^[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}$`)
+	mobileRegexp  = regexp.MustCompile(`^1[3-9]\d{9}$`)
+	numericRegexp = regexp.MustCompile(`^[-+]?\d+(\.\d+)?$`)
+)
+
+// newValidationRules returns the built-in rules
+func newValidationRules() *validationRules {
+	return &validationRules{rules: map[string]*validationRule{
+		"required": {
+			fn:       func(v reflect.Value, _ string) bool { return !v.IsZero() },
+			messages: map[string]string{validationLangZH: "{field}不能为空", validationLangEN: "{field} is required"},
+		},
+		"min": {
+			fn: func(v reflect.Value, param string) bool {
+				n, ok := validationNumber(v)
+				return ok && n >= validationParam(param)
+			},
+			messages:    map[string]string{validationLangZH: "{field}不能小于{param}", validationLangEN: "{field} must be at least {param}"},
+			lenMessages: map[string]string{validationLangZH: "{field}长度不能小于{param}", validationLangEN: "{field} length must be at least {param}"},
+			numberParam: true,
+		},
+		"max": {
+			fn: func(v reflect.Value, param string) bool {
+				n, ok := validationNumber(v)
+				return ok && n <= validationParam(param)
+			},
+			messages:    map[string]string{validationLangZH: "{field}不能大于{param}", validationLangEN: "{field} must be at most {param}"},
+			lenMessages: map[string]string{validationLangZH: "{field}长度不能大于{param}", validationLangEN: "{field} length must be at most {param}"},
+			numberParam: true,
+		},
+		"len": {
+			fn: func(v reflect.Value, param string) bool {
+				n, ok := validationNumber(v)
+				return ok && n == validationParam(param)
+			},
+			messages:    map[string]string{validationLangZH: "{field}必须等于{param}", validationLangEN: "{field} must be {param}"},
+			lenMessages: map[string]string{validationLangZH: "{field}长度必须是{param}", validationLangEN: "{field} length must be {param}"},
+			numberParam: true,
+		},
+		"in": {
+			fn: func(v reflect.Value, param string) bool {
+				s := validationString(v)
+				for _, option := range strings.Split(param, "|") {
+					if s == option {
+						return true
+					}
+				}
+				return false
+			},
+			messages: map[string]string{validationLangZH: "{field}必须是{param}之一", validationLangEN: "{field} must be one of {param}"},
+		},
+		"email": {
+			fn:       validationRegexp(emailRegexp),
+			messages: map[string]string{validationLangZH: "{field}不是有效的邮箱地址", validationLangEN: "{field} must be a valid email address"},
+		},
+		"mobile": {
+			fn:       validationRegexp(mobileRegexp),
+			messages: map[string]string{validationLangZH: "{field}不是有效的手机号", validationLangEN: "{field} must be a valid mobile number"},
+		},
+		"idcard": {
+			fn: func(v reflect.Value, _ string) bool {
+				return isIDCard(validationString(v))
+			},
+			messages: map[string]string{validationLangZH: "{field}不是有效的身份证号", validationLangEN: "{field} must be a valid ID card number"},
+		},
+		"url": {
+			fn: func(v reflect.Value, _ string) bool {
+				u, err := url.ParseRequestURI(validationString(v))
+				return err == nil && u.Scheme != "" && u.Host != ""
+			},
+			messages: map[string]string{validationLangZH: "{field}不是有效的URL", validationLangEN: "{field} must be a valid URL"},
+		},
+		"numeric": {
+			fn:       validationRegexp(numericRegexp),
+			messages: map[string]string{validationLangZH: "{field}必须是数字", validationLangEN: "{field} must be numeric"},
+		},
+	}}
+}
+
+// RegisterValidation registers a custom validation rule.
+//	r.RegisterValidation("objectid", gow.ValidationRule{
+//		Func: func(v reflect.Value, param string) bool {
+//			return objectIDRegexp.MatchString(v.String())
+//		},
+//		Messages: map[string]string{"zh": "{field}不是有效的ID", "en": "{field} must be a valid id"},
+//	})
+func (engine *Engine) RegisterValidation(name string, rule ValidationRule) {
+	assert1(isWordString(name), "validation rule name must match \\w+, has: '"+name+"'")
+	assert1(name != "omitempty", "validation rule name can not be omitempty")
+	assert1(rule.Func != nil, "validation rule '"+name+"' must have a Func")
+	rules := engine.validationRules
+	rules.rules[name] = &validationRule{fn: rule.Func, messages: rule.Messages}
+	// the checked types may use the new rule
+	rules.checked.Range(func(key, _ interface{}) bool {
+		rules.checked.Delete(key)
+		return true
+	})
+}
+
+// Validate validates obj, a struct or a pointer to a struct, by its validate tags.
+// It returns ValidationErrors when any field fails,
+// or an error when a tag has an unknown rule or an invalid param.
+func (c *Context) Validate(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	rules := c.engine.validationRules
+	if err := rules.check(v.Type()); err != nil {
+		return err
+	}
+	var errs ValidationErrors
+	if err := rules.validateStruct(v, "", c.validationLang(), &errs); err != nil {
+		return err
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validationLang returns zh or en by the Accept-Language header, or engine.ValidationLang
+func (c *Context) validationLang() string {
+	for _, lang := range parseAccept(c.GetHeader("Accept-Language")) {
+		lang = strings.ToLower(lang)
+		switch {
+		case strings.HasPrefix(lang, validationLangZH):
+			return validationLangZH
+		case strings.HasPrefix(lang, validationLangEN):
+			return validationLangEN
+		}
+	}
+	return c.engine.ValidationLang
+}
+
+// check checks the validate tags of the struct type t and the structs in it once
+func (rules *validationRules) check(t reflect.Type) error {
+	if err, ok := rules.checked.Load(t); ok {
+		if err == nil {
+			return nil
+		}
+		return err.(error)
+	}
+	err := rules.checkType(t, "", make(map[reflect.Type]bool))
+	if err == nil {
+		rules.checked.Store(t, nil)
+	} else {
+		rules.checked.Store(t, err)
+	}
+	return err
+}
+
+// checkType reports the first unknown rule or invalid param in the tags of t,
+// the types of interface fields are checked when they are validated
+func (rules *validationRules) checkType(t reflect.Type, prefix string, seen map[reflect.Type]bool) error {
+	seen[t] = true
+	defer delete(seen, t)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		path := prefix
+		if !field.Anonymous {
+			path += validationFieldName(field)
+		}
+		for _, opt := range strings.Split(tag, ",") {
+			name, param := opt, ""
+			if i := strings.IndexByte(opt, '='); i >= 0 {
+				name, param = opt[:i], opt[i+1:]
+			}
+			if name == "" || name == "omitempty" {
+				continue
+			}
+			rule, ok := rules.rules[name]
+			if !ok {
+				return fmt.Errorf("unknown validation rule '%s' of field '%s'", name, path)
+			}
+			if _, err := strconv.ParseFloat(param, 64); rule.numberParam && err != nil {
+				return fmt.Errorf("invalid validation param '%s' of field '%s'", opt, path)
+			}
+		}
+		if !field.Anonymous {
+			path += "."
+		}
+		ft := indirectType(field.Type)
+		if ft.Kind() == reflect.Slice || ft.Kind() == reflect.Array {
+			ft = indirectType(ft.Elem())
+			path = strings.TrimSuffix(path, ".") + "[]."
+		}
+		if ft.Kind() == reflect.Struct && !isBindLeaf(ft) && !seen[ft] {
+			if err := rules.checkType(ft, path, seen); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (rules *validationRules) validateStruct(v reflect.Value, prefix, lang string, errs *ValidationErrors) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+		tag := field.Tag.Get("validate")
+		if tag == "-" {
+			continue
+		}
+		path := prefix
+		if !field.Anonymous {
+			path += validationFieldName(field)
+		}
+		label := field.Tag.Get("label")
+		if label == "" {
+			label = path
+		}
+		if tag != "" && !rules.validateField(v.Field(i), tag, path, label, lang, errs) {
+			continue
+		}
+		if !field.Anonymous {
+			path += "."
+		}
+		if err := rules.dive(v.Field(i), path, lang, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// dive validates the structs in v, like nested structs and slices of structs
+func (rules *validationRules) dive(v reflect.Value, prefix, lang string, errs *ValidationErrors) error {
+	dynamic := false
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		dynamic = dynamic || v.Kind() == reflect.Interface
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if isBindLeaf(v.Type()) {
+			return nil
+		}
+		if dynamic {
+			if err := rules.check(v.Type()); err != nil {
+				return err
+			}
+		}
+		return rules.validateStruct(v, prefix, lang, errs)
+	case reflect.Slice, reflect.Array:
+		if indirectType(v.Type().Elem()).Kind() != reflect.Struct {
+			return nil
+		}
+		path := strings.TrimSuffix(prefix, ".")
+		for i := 0; i < v.Len(); i++ {
+			if err := rules.dive(v.Index(i), path+"["+strconv.Itoa(i)+"].", lang, errs); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateField runs the rules of the tag on v, it stops at the first failure and reports false.
+// The tag is already checked.
+func (rules *validationRules) validateField(v reflect.Value, tag, path, label, lang string, errs *ValidationErrors) bool {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			v = reflect.Value{}
+			break
+		}
+		v = v.Elem()
+	}
+	empty := !v.IsValid() || v.IsZero()
+
+	for _, opt := range strings.Split(tag, ",") {
+		name, param := opt, ""
+		if i := strings.IndexByte(opt, '='); i >= 0 {
+			name, param = opt[:i], opt[i+1:]
+		}
+		switch name {
+		case "":
+			continue
+		case "omitempty":
+			if empty {
+				return true
+			}
+			continue
+		}
+		rule := rules.rules[name]
+		if name == "required" {
+			if !empty {
+				continue
+			}
+		} else if !v.IsValid() || rule.fn(v, param) {
+			// a nil pointer is only checked by required
+			continue
+		}
+		*errs = append(*errs, &FieldError{
+			Field:   path,
+			Rule:    name,
+			Param:   param,
+			Message: rule.message(lang, v, label, param),
+		})
+		return false
+	}
+	return true
+}
+
+// message formats the message of the rule in lang
+func (r *validationRule) message(lang string, v reflect.Value, label, param string) string {
+	messages := r.messages
+	if r.lenMessages != nil && v.IsValid() {
+		switch v.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			messages = r.lenMessages
+		}
+	}
+	msg := messages[lang]
+	if msg == "" {
+		msg = messages[validationLangEN]
+	}
+	if msg == "" {
+		msg = "{field} is invalid"
+		if lang == validationLangZH {
+			msg = "{field}格式不正确"
+		}
+	}
+	return strings.NewReplacer("{field}", label, "{param}", strings.ReplaceAll(param, "|", ", ")).Replace(msg)
+}
+
+// validationFieldName returns the json name of a field, or its field name
+func validationFieldName(field reflect.StructField) string {
+	if name := strings.Split(field.Tag.Get("json"), ",")[0]; name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+// validationNumber returns the value of a number, or the length of a string, slice and map
+func validationNumber(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true
+	}
+	return 0, false
+}
+
+// validationParam parses a number param, it is already checked
+func validationParam(param string) float64 {
+	f, _ := strconv.ParseFloat(param, 64)
+	return f
+}
+
+// validationString returns the string of v, a value of an unexported field is formatted by its kind
+func validationString(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return v.String()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	}
+	if !v.CanInterface() {
+		return ""
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+func validationRegexp(re *regexp.Regexp) func(v reflect.Value, _ string) bool {
+	return func(v reflect.Value, _ string) bool {
+		return re.MatchString(validationString(v))
+	}
+}
+
+var idCardWeights = [17]int{7, 9, 10, 5, 8, 4, 2, 1, 6, 3, 7, 9, 10, 5, 8, 4, 2}
+
+// isIDCard reports whether s is a Chinese resident ID card number,
+// 18 digits with the check digit, or the legacy 15 digits
+func isIDCard(s string) bool {
+	switch len(s) {
+	case 18:
+		if !isDigitString(s[:17]) || !isIDCardDate(s[6:14]) {
+			return false
+		}
+		sum := 0
+		for i, w := range idCardWeights {
+			sum += int(s[i]-'0') * w
+		}
+		check := s[17]
+		if check == 'x' {
+			check = 'X'
+		}
+		return "10X98765432"[sum%11] == check
+	case 15:
+		return isDigitString(s) && isIDCardDate("19"+s[6:12])
+	}
+	return false
+}
+
+func isIDCardDate(s string) bool {
+	t, err := time.Parse("20060102", s)
+	return err == nil && !t.After(time.Now())
+}
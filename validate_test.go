@@ -0,0 +1,156 @@
+package gow
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type validateTestItem struct {
+	Name string `json:"name" validate:"required"`
+}
+
+type validateTestRequest struct {
+	Mobile string              `json:"mobile" validate:"required,mobile" label:"手机号"`
+	IDCard string              `json:"id_card" validate:"omitempty,idcard"`
+	Age    int                 `json:"age" validate:"min=18,max=120"`
+	Type   string              `json:"type" validate:"in=a|b|c"`
+	Nick   *string             `json:"nick" validate:"omitempty,len=3"`
+	Code   string              `json:"code" validate:"omitempty,even"`
+	Items  []*validateTestItem `json:"items" validate:"max=2"`
+}
+
+func newValidateTestEngine() *Engine {
+	r := New()
+	r.RegisterValidation("even", ValidationRule{
+		Func: func(v reflect.Value, _ string) bool {
+			return len(v.String())%2 == 0
+		},
+		Messages: map[string]string{"zh": "{field}长度必须是偶数", "en": "{field} length must be even"},
+	})
+	r.POST("/user", func(c *Context) {
+		var req validateTestRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.DataJSON(err)
+			return
+		}
+		c.DataJSON("ok")
+	})
+	return r
+}
+
+func TestContextValidate(t *testing.T) {
+	r := newValidateTestEngine()
+
+	tests := []struct {
+		body   string
+		lang   string
+		fields []string
+		msg    string
+	}{
+		{`{"mobile":"13800138000","id_card":"11010519491231002X","age":20,"type":"a","nick":"sam","code":"ab","items":[{"name":"x"}]}`, "", nil, "ok"},
+		{`{"age":20,"type":"a"}`, "", []string{"mobile"}, "手机号不能为空"},
+		{`{"mobile":"12345","age":20,"type":"a"}`, "en-US,en;q=0.9", []string{"mobile"}, "手机号 must be a valid mobile number"},
+		{`{"mobile":"13800138000","id_card":"110105194912310021","age":10,"type":"d"}`, "", []string{"id_card", "age", "type"}, "id_card不是有效的身份证号"},
+		{`{"mobile":"13800138000","age":20,"type":"a","nick":"sa","code":"abc"}`, "en", []string{"nick", "code"}, "nick length must be 3"},
+		{`{"mobile":"13800138000","age":20,"type":"a","items":[{"name":"x"},{}]}`, "", []string{"items[1].name"}, "items[1].name不能为空"},
+		{`{"mobile":"13800138000","age":20,"type":"a","items":[{},{},{}]}`, "zh-CN", []string{"items"}, "items长度不能大于2"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/user", strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", "application/json")
+		if tt.lang != "" {
+			req.Header.Set("Accept-Language", tt.lang)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		var resp struct {
+			Code int    `json:"code"`
+			Msg  string `json:"msg"`
+			Body struct {
+				Data json.RawMessage `json:"data"`
+			} `json:"body"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("%s: %v", w.Body.String(), err)
+		}
+		if tt.fields == nil {
+			if resp.Code != 0 || resp.Msg != tt.msg {
+				t.Errorf("%s: code = %d, msg = %q", tt.body, resp.Code, resp.Msg)
+			}
+			continue
+		}
+		var errs []FieldError
+		json.Unmarshal(resp.Body.Data, &errs)
+		var fields []string
+		for _, e := range errs {
+			fields = append(fields, e.Field)
+		}
+		if resp.Code != r.ValidationErrorCode || resp.Msg != tt.msg || strings.Join(fields, ",") != strings.Join(tt.fields, ",") {
+			t.Errorf("%s: code = %d, msg = %q, fields = %v, want %q, %v", tt.body, resp.Code, resp.Msg, fields, tt.msg, tt.fields)
+		}
+	}
+}
+
+type validateTestTags struct {
+	Name string `json:"name" validate:"required,objectid"`
+}
+
+type validateTestCode struct {
+	Code int `json:"code" validate:"in=1|2"`
+}
+
+type validateTestEmbedded struct {
+	validateTestCode
+	Next *validateTestEmbedded `json:"next"`
+	Any  interface{}           `json:"any"`
+}
+
+func TestContextValidateTags(t *testing.T) {
+	r := New()
+	c := &Context{engine: r, Request: httptest.NewRequest("GET", "/", nil)}
+
+	// unknown rules and invalid params are errors, not panics
+	for _, obj := range []interface{}{&validateTestTags{}, &struct {
+		Items []validateTestTags `json:"items"`
+	}{}, &struct {
+		Age int `validate:"min=x"`
+	}{}} {
+		err := c.Validate(obj)
+		if _, ok := err.(ValidationErrors); err == nil || ok {
+			t.Errorf("%T: err = %v", obj, err)
+		}
+	}
+	r.RegisterValidation("objectid", ValidationRule{Func: func(v reflect.Value, _ string) bool { return true }})
+	if err := c.Validate(&validateTestTags{Name: "x"}); err != nil {
+		t.Errorf("after RegisterValidation err = %v", err)
+	}
+
+	// the fields of an unexported embedded struct, a recursive type and the struct in an interface
+	obj := &validateTestEmbedded{Next: &validateTestEmbedded{}, Any: &validateTestTags{}}
+	obj.Code = 3
+	errs, ok := c.Validate(obj).(ValidationErrors)
+	if !ok || len(errs) != 3 || errs[0].Field != "code" || errs[1].Field != "next.code" || errs[2].Field != "any.name" {
+		t.Errorf("errs = %v", errs)
+	}
+}
+
+func TestIsIDCard(t *testing.T) {
+	tests := map[string]bool{
+		"11010519491231002X": true,
+		"11010519491231002x": true,
+		"110105194912310021": false,
+		"110105194913310023": false,
+		"110105491231002":    true,
+		"11010549123100":     false,
+		"":                   false,
+	}
+	for s, want := range tests {
+		if got := isIDCard(s); got != want {
+			t.Errorf("isIDCard(%q) = %v, want %v", s, got, want)
+		}
+	}
+}
@@ -0,0 +1,614 @@
+package gow
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocket message types, RFC 6455 section 11.8
+const (
+	TextMessage   = 1
+	BinaryMessage = 2
+	CloseMessage  = 8
+	PingMessage   = 9
+	PongMessage   = 10
+)
+
+// websocket close codes, RFC 6455 section 11.7
+const (
+	CloseNormalClosure           = 1000
+	CloseGoingAway               = 1001
+	CloseProtocolError           = 1002
+	CloseUnsupportedData         = 1003
+	CloseNoStatusReceived        = 1005
+	CloseAbnormalClosure         = 1006
+	CloseInvalidFramePayloadData = 1007
+	ClosePolicyViolation         = 1008
+	CloseMessageTooBig           = 1009
+	CloseInternalServerErr       = 1011
+)
+
+const (
+	websocketGUID             = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+	defaultWebsocketReadLimit = 1 << 20
+	defaultWebsocketWriteWait = 10 * time.Second
+	maxControlFramePayload    = 125
+)
+
+var (
+	// ErrWebsocketClosed write to a closed websocket connection
+	ErrWebsocketClosed = errors.New("websocket: use of closed connection")
+
+	errWebsocketMethod   = errors.New("websocket: the request method is not GET")
+	errWebsocketUpgrade  = errors.New("websocket: the request is not a websocket upgrade")
+	errWebsocketVersion  = errors.New("websocket: unsupported version, only 13 is supported")
+	errWebsocketKey      = errors.New("websocket: Sec-WebSocket-Key is missing or invalid")
+	errWebsocketOrigin   = errors.New("websocket: the request origin is not allowed")
+	errWebsocketHijack   = errors.New("websocket: the response does not implement http.Hijacker")
+	errWebsocketMessage  = errors.New("websocket: the message type must be TextMessage or BinaryMessage")
+	errWebsocketControl  = errors.New("websocket: the message type must be CloseMessage, PingMessage or PongMessage")
+	errWebsocketTooLarge = errors.New("websocket: control frame payload is larger than 125 bytes")
+)
+
+// WebsocketConfig config of Context.Upgrade
+type WebsocketConfig struct {
+	// Subprotocols the protocols supported by the server in order of preference
+	Subprotocols []string
+
+	// CheckOrigin return true to accept the request origin,
+	// the default accepts requests without Origin or with an Origin matching the Host
+	CheckOrigin func(r *http.Request) bool
+
+	// ReadLimit the max size in bytes of a message read from the peer, default 1MB,
+	// a larger message closes the connection with CloseMessageTooBig
+	ReadLimit int64
+
+	// WriteWait the write timeout of a message, default 10s
+	WriteWait time.Duration
+
+	// PingInterval send a ping to the peer at this interval, 0 disables it
+	PingInterval time.Duration
+
+	// PongWait the read timeout, it is extended when a pong is received,
+	// the default is twice the PingInterval
+	PongWait time.Duration
+}
+
+// WebsocketCloseError the connection was closed by a close frame,
+// Code is the close code sent by the peer or the code of the protocol error detected locally
+type WebsocketCloseError struct {
+	Code int
+	Text string
+}
+
+func (e *WebsocketCloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// IsWebsocketCloseError report whether err is a WebsocketCloseError with one of the codes
+func IsWebsocketCloseError(err error, codes ...int) bool {
+	var closeErr *WebsocketCloseError
+	if !errors.As(err, &closeErr) {
+		return false
+	}
+	for _, code := range codes {
+		if closeErr.Code == code {
+			return true
+		}
+	}
+	return len(codes) == 0
+}
+
+// WebsocketConn a websocket connection
+//	one goroutine may read and others may write concurrently,
+//	ReadMessage must not be called concurrently
+type WebsocketConn struct {
+	conn        net.Conn
+	br          *bufio.Reader
+	isServer    bool
+	subprotocol string
+
+	readLimit   int64
+	pongWait    time.Duration
+	pingHandler func(data string) error
+	pongHandler func(data string) error
+
+	writeMu   sync.Mutex
+	writeWait time.Duration
+	closed    bool
+	done      chan struct{}
+}
+
+// Upgrade upgrade the request to a websocket connection
+//	an error response is written when the handshake fails
+//	r.GET("/ws", func(c *gow.Context) {
+//		ws, err := c.Upgrade()
+//		if err != nil {
+//			return
+//		}
+//		defer ws.Close()
+//		for {
+//			mt, msg, err := ws.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			ws.WriteMessage(mt, msg)
+//		}
+//	})
+func (c *Context) Upgrade(config ...WebsocketConfig) (*WebsocketConn, error) {
+	var cfg WebsocketConfig
+	if len(config) > 0 {
+		cfg = config[0]
+	}
+	r := c.Request
+
+	if r.Method != http.MethodGet {
+		return nil, c.websocketError(http.StatusMethodNotAllowed, errWebsocketMethod)
+	}
+	if !c.IsWebsocket() {
+		return nil, c.websocketError(http.StatusBadRequest, errWebsocketUpgrade)
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		c.Header("Sec-WebSocket-Version", "13")
+		return nil, c.websocketError(http.StatusUpgradeRequired, errWebsocketVersion)
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if k, err := base64.StdEncoding.DecodeString(key); err != nil || len(k) != 16 {
+		return nil, c.websocketError(http.StatusBadRequest, errWebsocketKey)
+	}
+	checkOrigin := cfg.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(r) {
+		return nil, c.websocketError(http.StatusForbidden, errWebsocketOrigin)
+	}
+	if _, ok := c.writermem.ResponseWriter.(http.Hijacker); !ok {
+		return nil, c.websocketError(http.StatusInternalServerError, errWebsocketHijack)
+	}
+
+	subprotocol := selectSubprotocol(r, cfg.Subprotocols)
+	c.Writer.WriteHeader(http.StatusSwitchingProtocols)
+	conn, brw, err := c.Writer.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	var b strings.Builder
+	b.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	b.WriteString("Sec-WebSocket-Accept: " + websocketAccept(key) + "\r\n")
+	if subprotocol != "" {
+		b.WriteString("Sec-WebSocket-Protocol: " + subprotocol + "\r\n")
+	}
+	b.WriteString("\r\n")
+
+	conn.SetDeadline(time.Time{})
+	if _, err = io.WriteString(conn, b.String()); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	ws := newWebsocketConn(conn, brw.Reader, true, cfg)
+	ws.subprotocol = subprotocol
+	return ws, nil
+}
+
+// websocketError write the handshake error response
+func (c *Context) websocketError(code int, err error) error {
+	c.Header("Content-Type", ContentPlain)
+	c.AbortWithStatus(code)
+	c.Writer.WriteString(http.StatusText(code))
+	return err
+}
+
+func newWebsocketConn(conn net.Conn, br *bufio.Reader, isServer bool, cfg WebsocketConfig) *WebsocketConn {
+	ws := &WebsocketConn{
+		conn:      conn,
+		br:        br,
+		isServer:  isServer,
+		readLimit: cfg.ReadLimit,
+		writeWait: cfg.WriteWait,
+		pongWait:  cfg.PongWait,
+		done:      make(chan struct{}),
+	}
+	if ws.readLimit <= 0 {
+		ws.readLimit = defaultWebsocketReadLimit
+	}
+	if ws.writeWait <= 0 {
+		ws.writeWait = defaultWebsocketWriteWait
+	}
+	if cfg.PingInterval > 0 {
+		if ws.pongWait <= 0 {
+			ws.pongWait = 2 * cfg.PingInterval
+		}
+		go ws.pingLoop(cfg.PingInterval)
+	}
+	if ws.pongWait > 0 {
+		conn.SetReadDeadline(time.Now().Add(ws.pongWait))
+	}
+	return ws
+}
+
+// Subprotocol return the negotiated subprotocol
+func (ws *WebsocketConn) Subprotocol() string {
+	return ws.subprotocol
+}
+
+// RemoteAddr return the remote network address
+func (ws *WebsocketConn) RemoteAddr() net.Addr {
+	return ws.conn.RemoteAddr()
+}
+
+// LocalAddr return the local network address
+func (ws *WebsocketConn) LocalAddr() net.Addr {
+	return ws.conn.LocalAddr()
+}
+
+// SetReadLimit set the max size in bytes of a message read from the peer
+func (ws *WebsocketConn) SetReadLimit(limit int64) {
+	ws.readLimit = limit
+}
+
+// SetReadDeadline set the read deadline of the underlying connection
+func (ws *WebsocketConn) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+// SetPingHandler set the handler of the pings from the peer,
+// the default handler replies a pong with the same data
+func (ws *WebsocketConn) SetPingHandler(h func(data string) error) {
+	ws.pingHandler = h
+}
+
+// SetPongHandler set the handler of the pongs from the peer
+func (ws *WebsocketConn) SetPongHandler(h func(data string) error) {
+	ws.pongHandler = h
+}
+
+// ReadMessage read the next text or binary message, ping, pong and close frames are handled internally
+//	a *WebsocketCloseError is returned when the connection is closed by a close frame
+func (ws *WebsocketConn) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, opcode, payload, err := ws.readFrame(ws.readLimit - int64(len(data)))
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err = ws.handlePing(string(payload)); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			if ws.pongWait > 0 {
+				ws.conn.SetReadDeadline(time.Now().Add(ws.pongWait))
+			}
+			if ws.pongHandler != nil {
+				if err = ws.pongHandler(string(payload)); err != nil {
+					return 0, nil, err
+				}
+			}
+			continue
+		case CloseMessage:
+			return 0, nil, ws.handleClose(payload)
+		case 0:
+			if messageType == 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			if messageType != 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "expected continuation frame")
+			}
+			messageType = opcode
+		}
+		data = append(data, payload...)
+		if fin {
+			break
+		}
+	}
+	if messageType == TextMessage && !utf8.Valid(data) {
+		return 0, nil, ws.fail(CloseInvalidFramePayloadData, "invalid utf8 text")
+	}
+	return messageType, data, nil
+}
+
+// ReadJSON read the next message and decode it as JSON
+func (ws *WebsocketConn) ReadJSON(v interface{}) error {
+	_, data, err := ws.ReadMessage()
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// WriteMessage write a text or binary message
+func (ws *WebsocketConn) WriteMessage(messageType int, data []byte) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return errWebsocketMessage
+	}
+	return ws.writeFrame(messageType, data)
+}
+
+// WriteJSON encode v as JSON and write it as a text message
+func (ws *WebsocketConn) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	return ws.writeFrame(TextMessage, data)
+}
+
+// WriteControl write a close, ping or pong frame
+func (ws *WebsocketConn) WriteControl(messageType int, data []byte) error {
+	if messageType != CloseMessage && messageType != PingMessage && messageType != PongMessage {
+		return errWebsocketControl
+	}
+	if len(data) > maxControlFramePayload {
+		return errWebsocketTooLarge
+	}
+	return ws.writeFrame(messageType, data)
+}
+
+// Ping write a ping frame
+func (ws *WebsocketConn) Ping(data []byte) error {
+	return ws.WriteControl(PingMessage, data)
+}
+
+// Close send a normal close frame and close the connection
+func (ws *WebsocketConn) Close() error {
+	return ws.CloseWithCode(CloseNormalClosure, "")
+}
+
+// CloseWithCode send a close frame with the code and text and close the connection
+func (ws *WebsocketConn) CloseWithCode(code int, text string) error {
+	ws.writeFrame(CloseMessage, closePayload(code, text))
+	return ws.closeConn()
+}
+
+// Done return a channel that is closed when the connection is closed
+func (ws *WebsocketConn) Done() <-chan struct{} {
+	return ws.done
+}
+
+func (ws *WebsocketConn) closeConn() error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if ws.closed {
+		return nil
+	}
+	ws.closed = true
+	close(ws.done)
+	return ws.conn.Close()
+}
+
+func (ws *WebsocketConn) handlePing(data string) error {
+	if ws.pingHandler != nil {
+		return ws.pingHandler(data)
+	}
+	err := ws.WriteControl(PongMessage, []byte(data))
+	if err == ErrWebsocketClosed {
+		return nil
+	}
+	return err
+}
+
+// handleClose echo the close frame of the peer and close the connection
+func (ws *WebsocketConn) handleClose(payload []byte) error {
+	code, text := CloseNoStatusReceived, ""
+	switch {
+	case len(payload) == 1:
+		return ws.fail(CloseProtocolError, "invalid close payload")
+	case len(payload) >= 2:
+		code = int(binary.BigEndian.Uint16(payload))
+		text = string(payload[2:])
+		if !validCloseCode(code) {
+			return ws.fail(CloseProtocolError, "invalid close code")
+		}
+		if !utf8.Valid(payload[2:]) {
+			return ws.fail(CloseInvalidFramePayloadData, "invalid utf8 close text")
+		}
+	}
+	if code == CloseNoStatusReceived {
+		ws.writeFrame(CloseMessage, nil)
+	} else {
+		ws.writeFrame(CloseMessage, closePayload(code, ""))
+	}
+	ws.closeConn()
+	return &WebsocketCloseError{Code: code, Text: text}
+}
+
+// fail close the connection with a protocol error
+func (ws *WebsocketConn) fail(code int, text string) error {
+	ws.CloseWithCode(code, text)
+	return &WebsocketCloseError{Code: code, Text: text}
+}
+
+func (ws *WebsocketConn) pingLoop(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ws.done:
+			return
+		case <-ticker.C:
+			if err := ws.Ping(nil); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// readFrame read a frame, limit is the remaining size of the data message
+func (ws *WebsocketConn) readFrame(limit int64) (fin bool, opcode int, payload []byte, err error) {
+	var head [8]byte
+	if _, err = io.ReadFull(ws.br, head[:2]); err != nil {
+		return
+	}
+	fin = head[0]&0x80 != 0
+	opcode = int(head[0] & 0x0f)
+	masked := head[1]&0x80 != 0
+	length := uint64(head[1] & 0x7f)
+
+	if head[0]&0x70 != 0 {
+		return fin, opcode, nil, ws.fail(CloseProtocolError, "reserved bits are set")
+	}
+	switch opcode {
+	case 0, TextMessage, BinaryMessage:
+	case CloseMessage, PingMessage, PongMessage:
+		if !fin || length > maxControlFramePayload {
+			return fin, opcode, nil, ws.fail(CloseProtocolError, "invalid control frame")
+		}
+	default:
+		return fin, opcode, nil, ws.fail(CloseProtocolError, "unknown opcode")
+	}
+	if masked != ws.isServer {
+		return fin, opcode, nil, ws.fail(CloseProtocolError, "invalid frame mask")
+	}
+
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(ws.br, head[:2]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(head[:2]))
+	case 127:
+		if _, err = io.ReadFull(ws.br, head[:8]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(head[:8])
+	}
+	if opcode < CloseMessage && (length > 1<<63-1 || int64(length) > limit) {
+		return fin, opcode, nil, ws.fail(CloseMessageTooBig, "message is too big")
+	}
+
+	var key [4]byte
+	if masked {
+		if _, err = io.ReadFull(ws.br, key[:]); err != nil {
+			return
+		}
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.br, payload); err != nil {
+		return
+	}
+	if masked {
+		maskBytes(key, payload)
+	}
+	return
+}
+
+func (ws *WebsocketConn) writeFrame(opcode int, data []byte) error {
+	return ws.writeRaw(ws.frame(opcode, data))
+}
+
+// frame encode a single final frame, client frames are masked
+func (ws *WebsocketConn) frame(opcode int, data []byte) []byte {
+	buf := make([]byte, 0, len(data)+14)
+	buf = append(buf, 0x80|byte(opcode))
+	var maskBit byte
+	if !ws.isServer {
+		maskBit = 0x80
+	}
+	switch n := len(data); {
+	case n <= 125:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126, byte(n>>8), byte(n))
+	default:
+		buf = append(buf, maskBit|127, 0, 0, 0, 0, 0, 0, 0, 0)
+		binary.BigEndian.PutUint64(buf[2:], uint64(n))
+	}
+	if ws.isServer {
+		return append(buf, data...)
+	}
+	var key [4]byte
+	rand.Read(key[:])
+	buf = append(buf, key[:]...)
+	start := len(buf)
+	buf = append(buf, data...)
+	maskBytes(key, buf[start:])
+	return buf
+}
+
+func (ws *WebsocketConn) writeRaw(frame []byte) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	if ws.closed {
+		return ErrWebsocketClosed
+	}
+	ws.conn.SetWriteDeadline(time.Now().Add(ws.writeWait))
+	_, err := ws.conn.Write(frame)
+	return err
+}
+
+func maskBytes(key [4]byte, b []byte) {
+	for i := range b {
+		b[i] ^= key[i&3]
+	}
+}
+
+func closePayload(code int, text string) []byte {
+	if code == CloseNoStatusReceived {
+		return nil
+	}
+	if len(text) > maxControlFramePayload-2 {
+		text = text[:maxControlFramePayload-2]
+	}
+	buf := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(buf, uint16(code))
+	return append(buf, text...)
+}
+
+func validCloseCode(code int) bool {
+	switch {
+	case code >= 1000 && code <= 1003, code >= 1007 && code <= 1011:
+		return true
+	case code >= 3000 && code <= 4999:
+		return true
+	}
+	return false
+}
+
+func websocketAccept(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// sameOrigin accept requests without Origin or with an Origin matching the Host
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func selectSubprotocol(r *http.Request, supported []string) string {
+	for _, offered := range parseAccept(r.Header.Get("Sec-WebSocket-Protocol")) {
+		for _, p := range supported {
+			if p == offered {
+				return p
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,188 @@
+package gow
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+)
+
+// WebsocketHub group websocket connections by room and broadcast messages to them
+//	a connection may join several rooms, it leaves all of them when it is closed or a write fails
+//	hub := gow.NewWebsocketHub()
+//	r.GET("/room/{id}", func(c *gow.Context) {
+//		ws, err := c.Upgrade()
+//		if err != nil {
+//			return
+//		}
+//		hub.Join(c.Param("id"), ws)
+//		defer hub.Remove(ws)
+//		for {
+//			_, msg, err := ws.ReadMessage()
+//			if err != nil {
+//				return
+//			}
+//			hub.Broadcast(c.Param("id"), gow.TextMessage, msg)
+//		}
+//	})
+type WebsocketHub struct {
+	mu      sync.RWMutex
+	rooms   map[string]map[*WebsocketConn]struct{}
+	conns   map[*WebsocketConn]map[string]struct{}
+	watched map[*WebsocketConn]struct{} // connections whose close is watched, until they are closed
+}
+
+// NewWebsocketHub return a new WebsocketHub
+func NewWebsocketHub() *WebsocketHub {
+	return &WebsocketHub{
+		rooms:   make(map[string]map[*WebsocketConn]struct{}),
+		conns:   make(map[*WebsocketConn]map[string]struct{}),
+		watched: make(map[*WebsocketConn]struct{}),
+	}
+}
+
+// Join add the connection to the room,
+// the close of a connection is watched from its first Join so it leaves all rooms when closed
+func (h *WebsocketHub) Join(room string, ws *WebsocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.rooms[room] == nil {
+		h.rooms[room] = make(map[*WebsocketConn]struct{})
+	}
+	h.rooms[room][ws] = struct{}{}
+	if h.conns[ws] == nil {
+		h.conns[ws] = make(map[string]struct{})
+	}
+	if _, ok := h.watched[ws]; !ok {
+		h.watched[ws] = struct{}{}
+		go h.removeOnClose(ws)
+	}
+	h.conns[ws][room] = struct{}{}
+}
+
+// Leave remove the connection from the room
+func (h *WebsocketHub) Leave(room string, ws *WebsocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.leave(room, ws)
+}
+
+// Remove remove the connection from all rooms
+func (h *WebsocketHub) Remove(ws *WebsocketConn) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for room := range h.conns[ws] {
+		h.leave(room, ws)
+	}
+}
+
+// Rooms return the names of the rooms that have connections
+func (h *WebsocketHub) Rooms() []string {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	rooms := make([]string, 0, len(h.rooms))
+	for room := range h.rooms {
+		rooms = append(rooms, room)
+	}
+	sort.Strings(rooms)
+	return rooms
+}
+
+// Count return the number of connections in the room
+func (h *WebsocketHub) Count(room string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.rooms[room])
+}
+
+// Broadcast write the message to all connections in the room,
+// it returns the number of connections the message was written to
+func (h *WebsocketHub) Broadcast(room string, messageType int, data []byte) int {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return 0
+	}
+	h.mu.RLock()
+	conns := make([]*WebsocketConn, 0, len(h.rooms[room]))
+	for ws := range h.rooms[room] {
+		conns = append(conns, ws)
+	}
+	h.mu.RUnlock()
+	return h.broadcast(conns, messageType, data)
+}
+
+// BroadcastJSON encode v as JSON and write it to all connections in the room
+func (h *WebsocketHub) BroadcastJSON(room string, v interface{}) (int, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return 0, err
+	}
+	return h.Broadcast(room, TextMessage, data), nil
+}
+
+// BroadcastAll write the message to all connections of the hub
+func (h *WebsocketHub) BroadcastAll(messageType int, data []byte) int {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return 0
+	}
+	h.mu.RLock()
+	conns := make([]*WebsocketConn, 0, len(h.conns))
+	for ws := range h.conns {
+		conns = append(conns, ws)
+	}
+	h.mu.RUnlock()
+	return h.broadcast(conns, messageType, data)
+}
+
+// broadcast write the message concurrently so a slow connection does not delay the others,
+// the frame is encoded once as server frames are not masked
+func (h *WebsocketHub) broadcast(conns []*WebsocketConn, messageType int, data []byte) int {
+	if len(conns) == 0 {
+		return 0
+	}
+	frame := conns[0].frame(messageType, data)
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		sent int
+	)
+	for _, ws := range conns {
+		wg.Add(1)
+		go func(ws *WebsocketConn) {
+			defer wg.Done()
+			if err := ws.writeRaw(frame); err != nil {
+				h.Remove(ws)
+				ws.closeConn()
+				return
+			}
+			mu.Lock()
+			sent++
+			mu.Unlock()
+		}(ws)
+	}
+	wg.Wait()
+	return sent
+}
+
+func (h *WebsocketHub) leave(room string, ws *WebsocketConn) {
+	if conns := h.rooms[room]; conns != nil {
+		delete(conns, ws)
+		if len(conns) == 0 {
+			delete(h.rooms, room)
+		}
+	}
+	if rooms := h.conns[ws]; rooms != nil {
+		delete(rooms, room)
+		if len(rooms) == 0 {
+			delete(h.conns, ws)
+		}
+	}
+}
+
+func (h *WebsocketHub) removeOnClose(ws *WebsocketConn) {
+	<-ws.Done()
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for room := range h.conns[ws] {
+		h.leave(room, ws)
+	}
+	delete(h.watched, ws)
+}
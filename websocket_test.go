@@ -0,0 +1,217 @@
+package gow
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func dialWebsocket(t *testing.T, srv *httptest.Server, path string, header http.Header) (*WebsocketConn, *http.Response) {
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	req, _ := http.NewRequest("GET", srv.URL+path, nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	req.Write(conn)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, resp
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	return newWebsocketConn(conn, br, false, WebsocketConfig{}), resp
+}
+
+func TestContextUpgrade(t *testing.T) {
+	r := New()
+	r.GET("/echo", func(c *Context) {
+		ws, err := c.Upgrade(WebsocketConfig{Subprotocols: []string{"chat"}, ReadLimit: 1024})
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		for {
+			mt, msg, err := ws.ReadMessage()
+			if err != nil {
+				return
+			}
+			ws.WriteMessage(mt, msg)
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ws, resp := dialWebsocket(t, srv, "/echo", http.Header{"Sec-Websocket-Protocol": {"v2, chat"}})
+	if ws == nil {
+		t.Fatalf("status = %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" || resp.Header.Get("Sec-WebSocket-Protocol") != "chat" {
+		t.Errorf("header = %v", resp.Header)
+	}
+
+	for _, msg := range []string{"hello", strings.Repeat("x", 300)} {
+		ws.WriteMessage(TextMessage, []byte(msg))
+		mt, got, err := ws.ReadMessage()
+		if err != nil || mt != TextMessage || string(got) != msg {
+			t.Errorf("echo = %d, %d bytes, %v", mt, len(got), err)
+		}
+	}
+
+	var pong string
+	ws.SetPongHandler(func(data string) error {
+		pong = data
+		return nil
+	})
+	ws.Ping([]byte("ping"))
+	ws.WriteMessage(BinaryMessage, []byte{1})
+	if _, got, err := ws.ReadMessage(); err != nil || len(got) != 1 || pong != "ping" {
+		t.Errorf("pong = %q, %v", pong, err)
+	}
+
+	ws.WriteMessage(TextMessage, []byte(strings.Repeat("x", 2048)))
+	if _, _, err := ws.ReadMessage(); !IsWebsocketCloseError(err, CloseMessageTooBig) {
+		t.Errorf("read limit err = %v", err)
+	}
+}
+
+func TestContextUpgradeRejected(t *testing.T) {
+	r := New()
+	r.GET("/ws", func(c *Context) {
+		c.Upgrade()
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	tests := []struct {
+		header http.Header
+		code   int
+	}{
+		{http.Header{"Origin": {"http://evil.com"}}, http.StatusForbidden},
+		{http.Header{"Origin": {srv.URL}, "Sec-Websocket-Version": {"8"}}, http.StatusUpgradeRequired},
+		{http.Header{"Sec-Websocket-Key": {"short"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		if ws, resp := dialWebsocket(t, srv, "/ws", tt.header); ws != nil || resp.StatusCode != tt.code {
+			t.Errorf("%v: status = %d, want %d", tt.header, resp.StatusCode, tt.code)
+		}
+	}
+}
+
+func TestWebsocketHub(t *testing.T) {
+	hub := NewWebsocketHub()
+	joined := make(chan struct{}, 3)
+	r := New()
+	r.GET("/room/{id}", func(c *Context) {
+		ws, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		hub.Join(c.Param("id"), ws)
+		joined <- struct{}{}
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	a, _ := dialWebsocket(t, srv, "/room/1", nil)
+	b, _ := dialWebsocket(t, srv, "/room/1", nil)
+	c, _ := dialWebsocket(t, srv, "/room/2", nil)
+	for i := 0; i < 3; i++ {
+		<-joined
+	}
+	if rooms := hub.Rooms(); strings.Join(rooms, ",") != "1,2" || hub.Count("1") != 2 {
+		t.Fatalf("rooms = %v, count = %d", rooms, hub.Count("1"))
+	}
+
+	if n := hub.Broadcast("1", TextMessage, []byte("goal")); n != 2 {
+		t.Errorf("sent = %d, want 2", n)
+	}
+	for _, ws := range []*WebsocketConn{a, b} {
+		if _, msg, err := ws.ReadMessage(); err != nil || string(msg) != "goal" {
+			t.Errorf("msg = %q, %v", msg, err)
+		}
+	}
+	if n := hub.BroadcastAll(TextMessage, []byte("all")); n != 3 {
+		t.Errorf("sent = %d, want 3", n)
+	}
+	if _, msg, _ := c.ReadMessage(); string(msg) != "all" {
+		t.Errorf("msg = %q", msg)
+	}
+
+	a.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Count("1") != 1 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if hub.Count("1") != 1 {
+		t.Errorf("count = %d after close, want 1", hub.Count("1"))
+	}
+	b.Close()
+	c.Close()
+}
+
+func TestWebsocketHubWatchOnce(t *testing.T) {
+	hub := NewWebsocketHub()
+	joined := make(chan struct{}, 1)
+	r := New()
+	r.GET("/ws", func(c *Context) {
+		ws, err := c.Upgrade()
+		if err != nil {
+			return
+		}
+		defer ws.Close()
+		hub.Join("1", ws)
+		hub.Leave("1", ws)
+		hub.Join("2", ws)
+		hub.Join("3", ws)
+		joined <- struct{}{}
+		for {
+			if _, _, err := ws.ReadMessage(); err != nil {
+				return
+			}
+		}
+	})
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	ws, _ := dialWebsocket(t, srv, "/ws", nil)
+	<-joined
+	hub.mu.RLock()
+	watched := len(hub.watched)
+	hub.mu.RUnlock()
+	if watched != 1 || strings.Join(hub.Rooms(), ",") != "2,3" {
+		t.Fatalf("watched = %d, rooms = %v", watched, hub.Rooms())
+	}
+
+	ws.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for len(hub.Rooms()) != 0 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	hub.mu.RLock()
+	watched = len(hub.watched)
+	hub.mu.RUnlock()
+	if watched != 0 || len(hub.Rooms()) != 0 {
+		t.Errorf("watched = %d, rooms = %v after close", watched, hub.Rooms())
+	}
+}